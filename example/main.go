@@ -19,12 +19,16 @@ const (
 )
 
 func main() {
-	client := umami.NewClient(hostUrl, umami.WithSingleToken("admin", "umami"))
-
 	ctx := context.Background()
 
+	client, err := umami.New(ctx, hostUrl, umami.WithSingleToken("admin", "umami"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	go public(ctx)
 	go users(ctx, client)
+	go me(ctx, client)
 	go teams(ctx, client)
 	go events(ctx, client)
 	go sessions(ctx, client)
@@ -50,7 +54,7 @@ func public(ctx context.Context) {
 	client := umami.NewClient(hostUrl)
 	defer client.Close()
 
-	err := client.Public().Send(ctx, "Mozilla/5.0 (Linux; Android 13; SM-G981B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Mobile Safari/537.36", types.SendEventRequest{
+	_, err := client.Public().Send(ctx, "Mozilla/5.0 (Linux; Android 13; SM-G981B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/137.0.0.0 Mobile Safari/537.36", types.SendEventRequest{
 		Payload: types.SendEventPayload{
 			Website:  websiteID,
 			Hostname: "mywebsite.com",
@@ -92,7 +96,7 @@ func users(ctx context.Context, client umami.Client) {
 	logStruct("Created User", createUser)
 
 	// GET /api/admin/users
-	usrs, err := client.User().ListUsers(ctx)
+	usrs, err := client.Admin().ListAdminUsers(ctx, types.ListQueryParams{PageSize: "100"})
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -137,6 +141,44 @@ func users(ctx context.Context, client umami.Client) {
 	log.Printf("User Deleted: %+v", updateUser.ID)
 }
 
+/*
+Me
+
+GET /api/me
+GET /api/me/websites
+GET /api/me/teams
+POST /api/auth/verify
+*/
+func me(ctx context.Context, client umami.Client) {
+	// GET /api/me
+	current, err := client.Me().GetMe(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logStruct("Me", current)
+
+	// GET /api/me/websites
+	myWebsites, err := client.Me().GetMyWebsites(ctx, types.ListQueryParams{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	logStruct("My Websites", myWebsites)
+
+	// GET /api/me/teams
+	myTeams, err := client.Me().GetMyTeams(ctx, types.ListQueryParams{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	logStruct("My Teams", myTeams)
+
+	// POST /api/auth/verify
+	verified, err := client.Me().VerifyToken(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	logStruct("Verified User", verified)
+}
+
 /*
 Teams
 
@@ -7,10 +7,18 @@ import (
 
 // Public provides an endpoint to send events to Umami server.
 type Public interface {
-	// Send register event in umami
+	// Send register event in umami. Options forward visitor details such as the client IP.
+	// The response holds the session cache token to replay with WithCache.
 	//
 	// POST /api/send
-	Send(ctx context.Context, userAgent string, payload types.SendEventRequest) error
+	Send(ctx context.Context, userAgent string, payload types.SendEventRequest, opts ...SendOption) (types.SendResponse, error)
+
+	// SendBatch registers several events, split into requests of at most the client batch size.
+	// It returns one result per event, in order, so that failed events can be retried individually.
+	// The error is that of the first request that failed as a whole; its events carry it too.
+	//
+	// POST /api/batch
+	SendBatch(ctx context.Context, userAgent string, events []types.SendEventRequest, opts ...SendOption) ([]types.BatchItemResult, error)
 }
 
 // User defines user-related operations.
@@ -20,7 +28,9 @@ type User interface {
 	// POST /api/users
 	CreateUser(ctx context.Context, req types.CreateUserRequest) (types.User, error)
 
-	// ListUsers returns all users. Admin access is required.
+	// ListUsers returns the first page of all users. Admin access is required.
+	//
+	// Deprecated: use Admin.ListAdminUsers, which supports search, paging and ordering.
 	//
 	// GET /api/admin/users
 	ListUsers(ctx context.Context) (types.Users, error)
@@ -51,6 +61,57 @@ type User interface {
 	ListUserTeams(ctx context.Context, userId string, params types.ListQueryParams) (types.UserTeams, error)
 }
 
+// Admin defines instance-wide listings. Admin access is required.
+type Admin interface {
+	// ListAdminUsers returns all users of the instance.
+	//
+	// GET /api/admin/users
+	ListAdminUsers(ctx context.Context, params types.ListQueryParams) (types.Users, error)
+
+	// ListAdminWebsites returns all websites of the instance.
+	//
+	// GET /api/admin/websites
+	ListAdminWebsites(ctx context.Context, params types.ListQueryParams) (types.Websites, error)
+
+	// ListAdminTeams returns all teams of the instance.
+	//
+	// GET /api/admin/teams
+	ListAdminTeams(ctx context.Context, params types.ListQueryParams) (types.UserTeams, error)
+}
+
+// Me defines operations on the authenticated user, available to non-admin tokens.
+type Me interface {
+	// GetMe returns the authenticated user and its session details.
+	//
+	// GET /api/me
+	GetMe(ctx context.Context) (types.Me, error)
+
+	// GetMyWebsites gets all websites that belong to the authenticated user.
+	//
+	// GET /api/me/websites
+	GetMyWebsites(ctx context.Context, params types.ListQueryParams) (types.UserWebsites, error)
+
+	// GetMyTeams gets all teams the authenticated user is a member of.
+	//
+	// GET /api/me/teams
+	GetMyTeams(ctx context.Context, params types.ListQueryParams) (types.UserTeams, error)
+
+	// UpdateMyPassword changes the password of the authenticated user.
+	//
+	// POST /api/me/password
+	UpdateMyPassword(ctx context.Context, req types.UpdatePasswordRequest) (types.User, error)
+
+	// VerifyToken checks that the current token is valid and returns its user.
+	//
+	// POST /api/auth/verify
+	VerifyToken(ctx context.Context) (types.CurrentUser, error)
+
+	// Logout invalidates the current token.
+	//
+	// POST /api/auth/logout
+	Logout(ctx context.Context) error
+}
+
 // Team defines team-related operations.
 type Team interface {
 	// CreateTeam creates a new team.
@@ -236,6 +297,15 @@ type WebsiteStats interface {
 	GetWebsiteMetrics(ctx context.Context, websiteId string, params types.WebsiteMetricsQueryParams) ([]types.WebsiteMetric, error)
 }
 
+// Realtime provides the live activity of a website, as shown on Umami's realtime dashboard.
+type Realtime interface {
+	// GetRealtime returns the recent pageviews, events and sessions of a website,
+	// with visitor counts per country, URL and referrer.
+	//
+	// GET /api/realtime/:websiteId
+	GetRealtime(ctx context.Context, websiteId string, params types.RealtimeQueryParams) (types.Realtime, error)
+}
+
 // Report provides structured access to Umami's reporting endpoints.
 type Report interface {
 	// GetInsights dive deeper into your data by using segments and filters.
@@ -277,4 +347,38 @@ type Report interface {
 	//
 	// POST /api/reports/attribution
 	GetAttribution(ctx context.Context, payload types.ReportAttributionRequest) (types.ReportAttribution, error)
+
+	// ListReports returns the saved reports accessible to the user.
+	//
+	// GET /api/reports
+	ListReports(ctx context.Context, params types.ListQueryParams) (types.SavedReports, error)
+
+	// ListWebsiteReports returns the saved reports of a website.
+	//
+	// GET /api/websites/:websiteId/reports
+	ListWebsiteReports(ctx context.Context, websiteId string, params types.ListQueryParams) (types.SavedReports, error)
+
+	// CreateReport saves a named report.
+	//
+	// POST /api/reports
+	CreateReport(ctx context.Context, req types.SaveReportRequest) (types.SavedReport, error)
+
+	// GetReport gets a saved report by ID.
+	//
+	// GET /api/reports/:reportId
+	GetReport(ctx context.Context, reportId string) (types.SavedReport, error)
+
+	// UpdateReport updates a saved report.
+	//
+	// POST /api/reports/:reportId
+	UpdateReport(ctx context.Context, reportId string, req types.SaveReportRequest) (types.SavedReport, error)
+
+	// DeleteReport deletes a saved report.
+	//
+	// DELETE /api/reports/:reportId
+	DeleteReport(ctx context.Context, reportId string) error
+
+	// RunSaved fetches a saved report and runs it through the typed method matching its type.
+	// The result has the same type as that method's, e.g. []types.ReportFunnel for a funnel.
+	RunSaved(ctx context.Context, reportId string) (any, error)
 }
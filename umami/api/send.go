@@ -0,0 +1,56 @@
+package api
+
+// SendOptions holds the per-send settings of Public.Send and Public.SendBatch.
+type SendOptions struct {
+	// ClientIP is the visitor IP, forwarded so that Umami geolocates the visitor
+	// rather than the server sending the event.
+	ClientIP string
+	// AcceptLanguage is the visitor's Accept-Language header.
+	AcceptLanguage string
+	// Headers are extra request headers.
+	Headers map[string]string
+}
+
+// SendOption configures a single send.
+type SendOption func(*SendOptions)
+
+// NewSendOptions applies opts in order.
+func NewSendOptions(opts ...SendOption) SendOptions {
+	var o SendOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClientIP forwards the visitor IP through the X-Forwarded-For and X-Real-IP headers,
+// and through the payload ip field unless the payload sets one.
+func WithClientIP(ip string) SendOption {
+	return func(o *SendOptions) {
+		o.ClientIP = ip
+	}
+}
+
+// WithAcceptLanguage forwards the visitor's Accept-Language header.
+func WithAcceptLanguage(value string) SendOption {
+	return func(o *SendOptions) {
+		o.AcceptLanguage = value
+	}
+}
+
+// WithCache replays the cache token returned by a previous send through the x-umami-cache
+// header, so that the event joins the same session without Umami resolving it again.
+func WithCache(token string) SendOption {
+	return WithHeader("x-umami-cache", token)
+}
+
+// WithHeader sets an extra request header, e.g. the client IP header configured on
+// the Umami server through CLIENT_IP_HEADER.
+func WithHeader(name, value string) SendOption {
+	return func(o *SendOptions) {
+		if o.Headers == nil {
+			o.Headers = map[string]string{}
+		}
+		o.Headers[name] = value
+	}
+}
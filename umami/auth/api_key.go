@@ -1,5 +1,7 @@
 package auth
 
+import "context"
+
 type ApiKeyAuth struct {
 	key string
 }
@@ -8,7 +10,7 @@ func NewApiKeyAuth(key string) Auth {
 	return &ApiKeyAuth{key: key}
 }
 
-func (a *ApiKeyAuth) Get() (string, error) {
+func (a *ApiKeyAuth) Get(context.Context) (string, error) {
 	return a.key, nil
 }
 
@@ -1,6 +1,16 @@
 package auth
 
+import "context"
+
 type Auth interface {
 	Header() string
-	Get() (string, error)
+	Get(ctx context.Context) (string, error)
+}
+
+// Renewer is implemented by token-based Auth implementations that can log in again
+// when the server rejects their token with a 401.
+type Renewer interface {
+	// Renew replaces stale, the value returned by Get that was rejected, with a fresh token.
+	// Concurrent calls for the same stale value result in a single login.
+	Renew(ctx context.Context, stale string) error
 }
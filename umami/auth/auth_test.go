@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"github.com/AdamShannag/umami-client/umami/auth"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -16,7 +18,7 @@ func TestSingleTokenAuth(t *testing.T) {
 		t.Errorf("expected header Authorization, got %s", header)
 	}
 
-	token, err := a.Get()
+	token, err := a.Get(context.Background())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -33,7 +35,7 @@ func TestApiKeyAuth(t *testing.T) {
 		t.Errorf("expected header x-umami-api-key, got %s", header)
 	}
 
-	token, err := a.Get()
+	token, err := a.Get(context.Background())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -50,7 +52,7 @@ func TestDefaultAuth(t *testing.T) {
 		t.Errorf("expected empty header, got %s", header)
 	}
 
-	token, err := a.Get()
+	token, err := a.Get(context.Background())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -76,7 +78,7 @@ func TestTokenRefresherAuth_Success(t *testing.T) {
 		t.Errorf("expected header Authorization, got %s", header)
 	}
 
-	token, err := a.Get()
+	token, err := a.Get(context.Background())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -99,8 +101,99 @@ func TestTokenRefresherAuth_Error(t *testing.T) {
 
 	a := auth.NewTokenRefresherAuth(ctx, getToken)
 
-	_, err := a.Get()
+	_, err := a.Get(context.Background())
 	if err == nil {
 		t.Errorf("expected error, got nil")
 	}
 }
+
+func TestLazyTokenAuth(t *testing.T) {
+	calls := 0
+	a := auth.NewLazyTokenAuth(func(ctx context.Context) (string, error) {
+		calls++
+		if calls == 1 {
+			return "", errors.New("umami unavailable")
+		}
+		return "lazy-token", nil
+	})
+
+	if calls != 0 {
+		t.Fatalf("expected no login before first Get, got %d", calls)
+	}
+
+	if _, err := a.Get(context.Background()); err == nil {
+		t.Errorf("expected error from failed login")
+	}
+
+	for i := 0; i < 2; i++ {
+		token, err := a.Get(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token != "Bearer lazy-token" {
+			t.Errorf("expected token 'Bearer lazy-token', got %s", token)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("expected 2 login attempts, got %d", calls)
+	}
+}
+
+func TestMemoryTokenStore(t *testing.T) {
+	ctx := context.Background()
+	s := auth.NewMemoryTokenStore()
+
+	if _, _, err := s.Load(ctx, "missing"); !errors.Is(err, auth.ErrTokenNotFound) {
+		t.Errorf("expected ErrTokenNotFound, got %v", err)
+	}
+
+	exp := time.Now().Add(time.Hour)
+	if err := s.Save(ctx, "key", "abc", exp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	token, gotExp, err := s.Load(ctx, "key")
+	if err != nil || token != "abc" || !gotExp.Equal(exp) {
+		t.Errorf("unexpected load result: %q %v %v", token, gotExp, err)
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tokens.json")
+
+	s := auth.NewFileTokenStore(path)
+	if _, _, err := s.Load(ctx, "missing"); !errors.Is(err, auth.ErrTokenNotFound) {
+		t.Errorf("expected ErrTokenNotFound, got %v", err)
+	}
+
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	keyA := auth.StoreKey("https://a.example.com", "admin")
+	keyB := auth.StoreKey("https://b.example.com", "admin")
+	if err := s.Save(ctx, keyA, "token-a", exp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Save(ctx, keyB, "token-b", exp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected 0600 permissions, got %v", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected no leftover temp files, got %d entries", len(entries))
+	}
+
+	token, gotExp, err := auth.NewFileTokenStore(path).Load(ctx, keyA)
+	if err != nil || token != "token-a" || !gotExp.Equal(exp) {
+		t.Errorf("unexpected load result: %q %v %v", token, gotExp, err)
+	}
+}
@@ -1,5 +1,7 @@
 package auth
 
+import "context"
+
 type DefaultAuth struct {
 }
 
@@ -7,7 +9,7 @@ func NewDefaultAuth() Auth {
 	return &DefaultAuth{}
 }
 
-func (a *DefaultAuth) Get() (string, error) {
+func (a *DefaultAuth) Get(context.Context) (string, error) {
 	return "", nil
 }
 
@@ -0,0 +1,9 @@
+package auth
+
+import "context"
+
+// NewLazyTokenAuth logs in on the first call to Get and reuses the token afterwards,
+// logging in again when the server rejects it. A failed login is retried on the next call.
+func NewLazyTokenAuth(login func(context.Context) (string, error)) Auth {
+	return &SingleTokenAuth{login: login}
+}
@@ -1,17 +1,73 @@
 package auth
 
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrNotRenewable is returned by Renew when the auth was created without a login function.
+var ErrNotRenewable = errors.New("auth: token cannot be renewed")
+
 type SingleTokenAuth struct {
+	mu    sync.RWMutex
 	token string
+	login func(context.Context) (string, error)
 }
 
 func NewSingleTokenAuth(token string) Auth {
 	return &SingleTokenAuth{token: token}
 }
 
-func (a *SingleTokenAuth) Get() (string, error) {
+// NewRenewableTokenAuth uses token until the server rejects it, then calls login for a new one.
+func NewRenewableTokenAuth(token string, login func(context.Context) (string, error)) Auth {
+	return &SingleTokenAuth{token: token, login: login}
+}
+
+func (a *SingleTokenAuth) Get(ctx context.Context) (string, error) {
+	a.mu.RLock()
+	token := a.token
+	a.mu.RUnlock()
+
+	if token != "" || a.login == nil {
+		return "Bearer " + token, nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token == "" {
+		token, err := a.login(ctx)
+		if err != nil {
+			return "", err
+		}
+		a.token = token
+	}
+
 	return "Bearer " + a.token, nil
 }
 
+func (a *SingleTokenAuth) Renew(ctx context.Context, stale string) error {
+	if a.login == nil {
+		return ErrNotRenewable
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if "Bearer "+a.token != stale {
+		// Another caller already renewed the token.
+		return nil
+	}
+
+	token, err := a.login(ctx)
+	if err != nil {
+		return err
+	}
+	a.token = token
+	return nil
+}
+
 func (a *SingleTokenAuth) Header() string {
 	return "Authorization"
 }
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrTokenNotFound is returned by TokenStore.Load when no token is stored under the key.
+var ErrTokenNotFound = errors.New("auth: token not found")
+
+// TokenStore persists login tokens so that restarts can reuse a still-valid token
+// instead of calling /api/auth/login again.
+type TokenStore interface {
+	// Load returns the token stored under key and when it expires, or ErrTokenNotFound.
+	Load(ctx context.Context, key string) (token string, expiresAt time.Time, err error)
+	// Save stores token under key, replacing any previous one.
+	Save(ctx context.Context, key, token string, expiresAt time.Time) error
+}
+
+// StoreKey returns the key a token is stored under for the given Umami host and username.
+func StoreKey(hostURL, username string) string {
+	return hostURL + "|" + username
+}
+
+type storedToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// MemoryTokenStore keeps tokens in memory, sharing them between clients of the same process.
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]storedToken
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: map[string]storedToken{}}
+}
+
+func (s *MemoryTokenStore) Load(_ context.Context, key string) (string, time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.tokens[key]
+	if !ok {
+		return "", time.Time{}, ErrTokenNotFound
+	}
+	return t.Token, t.ExpiresAt, nil
+}
+
+func (s *MemoryTokenStore) Save(_ context.Context, key, token string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokens[key] = storedToken{Token: token, ExpiresAt: expiresAt}
+	return nil
+}
+
+// FileTokenStore keeps tokens in a JSON file readable only by the current user (0600).
+// Writes go to a temporary file that is renamed over the previous one, so the file is
+// never left half-written.
+type FileTokenStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+func (s *FileTokenStore) Load(_ context.Context, key string) (string, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	t, ok := tokens[key]
+	if !ok {
+		return "", time.Time{}, ErrTokenNotFound
+	}
+	return t.Token, t.ExpiresAt, nil
+}
+
+func (s *FileTokenStore) Save(_ context.Context, key, token string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	tokens[key] = storedToken{Token: token, ExpiresAt: expiresAt}
+
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return fmt.Errorf("marshal tokens: %w", err)
+	}
+
+	return writeFileAtomic(s.path, data)
+}
+
+func (s *FileTokenStore) read() (map[string]storedToken, error) {
+	tokens := map[string]storedToken{}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read token store: %w", err)
+	}
+
+	if err = json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("decode token store: %w", err)
+	}
+	return tokens, nil
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("create token store dir: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = tmp.Chmod(0o600); err == nil {
+		if _, err = tmp.Write(data); err == nil {
+			err = tmp.Sync()
+		}
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("write token store: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replace token store: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package token
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Expiry returns the exp claim of a JWT. The signature is not verified; ok is false
+// when the token is opaque or carries no exp claim.
+func Expiry(token string) (exp time.Time, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp *json.Number `json:"exp"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+
+	secs, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, int64(secs*float64(time.Second))), true
+}
+
+// Lifetime returns the time left before token expires according to its exp claim,
+// or fallback when the token is not a JWT with an exp claim.
+func Lifetime(token string, fallback time.Duration) time.Duration {
+	if exp, ok := Expiry(token); ok {
+		return time.Until(exp)
+	}
+	return fallback
+}
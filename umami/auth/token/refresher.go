@@ -2,48 +2,182 @@ package token
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	defaultRefreshFraction = 0.8
+	retryInterval          = 5 * time.Second
+	minRefreshInterval     = time.Second
+)
+
+// ErrClosed is returned by Get and Renew once the refresher's context is done.
+var ErrClosed = errors.New("token: refresher closed")
+
 type tokenResponse struct {
-	Token string
-	Err   error
+	Token   string
+	Expires time.Time
+	Err     error
+}
+
+// Option configures a Refresher.
+type Option func(*Refresher)
+
+// WithRefreshFraction refreshes the token once the given fraction (0-1] of its remaining lifetime has elapsed.
+func WithRefreshFraction(f float64) Option {
+	return func(t *Refresher) {
+		if f > 0 && f <= 1 {
+			t.fraction = f
+		}
+	}
 }
 
+// Refresher keeps a token fresh in the background. Reads of the current token are lock-free.
 type Refresher struct {
-	accessToken chan tokenResponse
+	current     atomic.Pointer[tokenResponse]
+	nextRefresh atomic.Int64
+
+	// mu serialises logins between the background refresh and Renew.
+	mu          sync.Mutex
 	authorize   func() (string, time.Duration, error)
+	fraction    float64
+	ready       chan struct{}
+	done        chan struct{}
+	rescheduled chan struct{}
 }
 
-func NewRefresher(ctx context.Context, auth func() (string, time.Duration, error)) *Refresher {
+// NewRefresher fetches a token with auth and keeps it fresh in the background until ctx is done.
+//
+// The token lifetime is read from the exp claim when the token is a JWT; otherwise the
+// duration returned by auth is used.
+func NewRefresher(ctx context.Context, auth func() (string, time.Duration, error), opts ...Option) *Refresher {
 	t := &Refresher{
-		accessToken: make(chan tokenResponse),
 		authorize:   auth,
+		fraction:    defaultRefreshFraction,
+		ready:       make(chan struct{}),
+		done:        make(chan struct{}),
+		rescheduled: make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(t)
 	}
 	go t.refresh(ctx)
 	return t
 }
 
 func (t *Refresher) refresh(ctx context.Context) {
-	token, expiresIn, err := t.authorize()
-	expired := time.After(expiresIn - 10*time.Second)
+	defer close(t.done)
+
+	t.mu.Lock()
+	t.login(false)
+	t.mu.Unlock()
+	close(t.ready)
+
+	timer := time.NewTimer(time.Until(t.NextRefresh()))
+	defer timer.Stop()
 
 	for {
 		select {
-		case t.accessToken <- tokenResponse{Token: token, Err: err}:
-		case <-expired:
-			token, expiresIn, err = t.authorize()
-			if err != nil {
-				expiresIn = 5 * time.Second
-			}
-			expired = time.After(expiresIn - 10*time.Second)
+		case <-timer.C:
+			t.mu.Lock()
+			t.login(true)
+			t.mu.Unlock()
+			timer.Reset(time.Until(t.NextRefresh()))
+		case <-t.rescheduled:
+			timer.Reset(time.Until(t.NextRefresh()))
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func (t *Refresher) Get() (string, error) {
-	res := <-t.accessToken
-	return res.Token, res.Err
+// login authorizes, publishes the result and schedules the next refresh. When keepValid is set and
+// the login fails, the current token stays in use until it expires. t.mu must be held.
+func (t *Refresher) login(keepValid bool) error {
+	token, expiresIn, err := t.authorize()
+	now := time.Now()
+
+	if err != nil {
+		t.nextRefresh.Store(now.Add(retryInterval).UnixNano())
+		if prev := t.current.Load(); keepValid && prev != nil && prev.Err == nil && now.Before(prev.Expires) {
+			return err
+		}
+		t.current.Store(&tokenResponse{Err: err})
+		return err
+	}
+
+	lifetime := Lifetime(token, expiresIn)
+	next := max(time.Duration(float64(lifetime)*t.fraction), minRefreshInterval)
+	t.nextRefresh.Store(now.Add(next).UnixNano())
+	t.current.Store(&tokenResponse{Token: token, Expires: now.Add(lifetime)})
+	return nil
+}
+
+// Get returns the current token, waiting for the first login to complete if needed.
+func (t *Refresher) Get(ctx context.Context) (string, error) {
+	select {
+	case <-t.done:
+		return "", ErrClosed
+	default:
+	}
+
+	if res := t.current.Load(); res != nil {
+		return res.Token, res.Err
+	}
+
+	select {
+	case <-t.ready:
+		res := t.current.Load()
+		return res.Token, res.Err
+	case <-t.done:
+		return "", ErrClosed
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// NextRefresh returns when the token will next be refreshed, or the zero time before the first login.
+func (t *Refresher) NextRefresh() time.Time {
+	n := t.nextRefresh.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// Renew forces a new login if stale is still the current token, e.g. after the server rejected it.
+// Concurrent calls for the same stale token result in a single login.
+func (t *Refresher) Renew(ctx context.Context, stale string) error {
+	select {
+	case <-t.ready:
+	case <-t.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	select {
+	case <-t.done:
+		return ErrClosed
+	default:
+	}
+
+	if res := t.current.Load(); res.Token != stale {
+		return res.Err
+	}
+
+	// The stale token was rejected, so a failed login is not hidden behind it.
+	err := t.login(false)
+	select {
+	case t.rescheduled <- struct{}{}:
+	default:
+	}
+
+	return err
 }
@@ -2,6 +2,9 @@ package token_test
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"github.com/AdamShannag/umami-client/umami/auth/token"
 	"testing"
 	"time"
@@ -17,7 +20,7 @@ func TestRefresher_GetAndRefresh(t *testing.T) {
 
 	refresher := token.NewRefresher(ctx, authorize)
 
-	token1, err := refresher.Get()
+	token1, err := refresher.Get(ctx)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -27,3 +30,179 @@ func TestRefresher_GetAndRefresh(t *testing.T) {
 
 	cancel()
 }
+
+func TestRefresher_Renew(t *testing.T) {
+	calls := 0
+	authorize := func() (string, time.Duration, error) {
+		calls++
+		return fmt.Sprintf("token%d", calls), time.Hour, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	refresher := token.NewRefresher(ctx, authorize)
+
+	stale, _ := refresher.Get(ctx)
+	for i := 0; i < 3; i++ {
+		if err := refresher.Renew(ctx, stale); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	got, _ := refresher.Get(ctx)
+	if got != "token2" {
+		t.Errorf("expected token2, got %s", got)
+	}
+	if calls != 2 {
+		t.Errorf("expected a single renewal for the same stale token, got %d logins", calls)
+	}
+}
+
+func TestRefresher_KeepsValidTokenWhenRefreshFails(t *testing.T) {
+	failed := make(chan struct{})
+	calls := 0
+	authorize := func() (string, time.Duration, error) {
+		calls++
+		if calls == 1 {
+			return "token1", 3 * time.Second, nil
+		}
+		if calls == 2 {
+			close(failed)
+		}
+		return "", 0, errors.New("login unavailable")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	refresher := token.NewRefresher(ctx, authorize, token.WithRefreshFraction(0.4))
+	if _, err := refresher.Get(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case <-failed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("token was not refreshed")
+	}
+
+	got, err := refresher.Get(ctx)
+	if err != nil || got != "token1" {
+		t.Errorf("expected the unexpired token1, got %q, %v", got, err)
+	}
+	if next := time.Until(refresher.NextRefresh()); next <= 0 || next > 5*time.Second {
+		t.Errorf("expected a retry to be scheduled, next refresh in %s", next)
+	}
+}
+
+func jwtWithExp(exp time.Time) string {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload := enc.EncodeToString([]byte(fmt.Sprintf(`{"userId":"u1","exp":%d}`, exp.Unix())))
+	return header + "." + payload + ".signature"
+}
+
+func TestExpiry(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	got, ok := token.Expiry(jwtWithExp(exp))
+	if !ok || !got.Equal(exp) {
+		t.Errorf("expected exp %v, got %v (ok=%v)", exp, got, ok)
+	}
+
+	if _, ok = token.Expiry("opaque-token"); ok {
+		t.Errorf("expected opaque token to have no expiry")
+	}
+
+	if d := token.Lifetime("opaque-token", time.Minute); d != time.Minute {
+		t.Errorf("expected fallback lifetime, got %v", d)
+	}
+}
+
+func TestRefresher_NextRefreshFromJWT(t *testing.T) {
+	jwt := jwtWithExp(time.Now().Add(100 * time.Second))
+	authorize := func() (string, time.Duration, error) {
+		return jwt, 24 * time.Hour, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	refresher := token.NewRefresher(ctx, authorize, token.WithRefreshFraction(0.5))
+	if _, err := refresher.Get(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	until := time.Until(refresher.NextRefresh())
+	if until < 45*time.Second || until > 50*time.Second {
+		t.Errorf("expected refresh in ~50s from the exp claim, got %v", until)
+	}
+}
+
+func TestRefresher_GetAfterClose(t *testing.T) {
+	authorize := func() (string, time.Duration, error) {
+		return "token1", time.Hour, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	refresher := token.NewRefresher(ctx, authorize)
+	if _, err := refresher.Get(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cancel()
+
+	deadline := time.After(time.Second)
+	for {
+		_, err := refresher.Get(context.Background())
+		if errors.Is(err, token.ErrClosed) {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("expected ErrClosed after cancel, got %v", err)
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestRefresher_GetHonoursContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	authorize := func() (string, time.Duration, error) {
+		<-release
+		return "token1", time.Hour, nil
+	}
+
+	refresher := token.NewRefresher(context.Background(), authorize)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := refresher.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded while waiting for the first login, got %v", err)
+	}
+}
+
+func BenchmarkRefresher_Get(b *testing.B) {
+	authorize := func() (string, time.Duration, error) {
+		return "token1", time.Hour, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	refresher := token.NewRefresher(ctx, authorize)
+	if _, err := refresher.Get(ctx); err != nil {
+		b.Fatalf("unexpected error: %v", err)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := refresher.Get(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
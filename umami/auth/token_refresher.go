@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"github.com/AdamShannag/umami-client/umami/auth/token"
+	"strings"
 	"time"
 )
 
@@ -10,15 +11,24 @@ type TokenRefresherAuth struct {
 	token *token.Refresher
 }
 
-func NewTokenRefresherAuth(ctx context.Context, GetTokenFunc func() (string, time.Duration, error)) Auth {
-	return &TokenRefresherAuth{token: token.NewRefresher(ctx, GetTokenFunc)}
+func NewTokenRefresherAuth(ctx context.Context, GetTokenFunc func() (string, time.Duration, error), opts ...token.Option) Auth {
+	return &TokenRefresherAuth{token: token.NewRefresher(ctx, GetTokenFunc, opts...)}
 }
 
-func (a *TokenRefresherAuth) Get() (string, error) {
-	get, err := a.token.Get()
+func (a *TokenRefresherAuth) Get(ctx context.Context) (string, error) {
+	get, err := a.token.Get(ctx)
 	return "Bearer " + get, err
 }
 
+func (a *TokenRefresherAuth) Renew(ctx context.Context, stale string) error {
+	return a.token.Renew(ctx, strings.TrimPrefix(stale, "Bearer "))
+}
+
+// NextRefresh returns when the token will next be refreshed.
+func (a *TokenRefresherAuth) NextRefresh() time.Time {
+	return a.token.NextRefresh()
+}
+
 func (a *TokenRefresherAuth) Header() string {
 	return "Authorization"
 }
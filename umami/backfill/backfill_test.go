@@ -0,0 +1,146 @@
+package backfill_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AdamShannag/umami-client/umami/backfill"
+	"github.com/AdamShannag/umami-client/umami/internal/publictest"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failOn returns a fake Umami failing the event sent for url.
+func failOn(url string) *publictest.Public {
+	return &publictest.Public{Err: func(event types.SendEventRequest) error {
+		if event.Payload.URL == url {
+			return errors.New("unavailable")
+		}
+		return nil
+	}}
+}
+
+// jsonl returns n records spread over sessions s0..s(sessions-1), one minute apart.
+func jsonl(n, sessions int) string {
+	var b strings.Builder
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range n {
+		fmt.Fprintf(&b, `{"time":%q,"userAgent":"ua","ip":"203.0.113.7","session":"s%d","event":{"type":"event","payload":{"website":"w1","url":"/%d"}}}`+"\n",
+			start.Add(time.Duration(i)*time.Minute).Format(time.RFC3339), i%sessions, i)
+		if i == 2 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func TestRunner_SendsWithTimestampsInSessionOrder(t *testing.T) {
+	public := &publictest.Public{}
+	runner := backfill.NewRunner(public, backfill.WithWorkers(3))
+
+	stats, err := runner.Run(context.Background(), backfill.NewJSONLReader(strings.NewReader(jsonl(30, 4))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := public.Calls()
+	if stats.Sent != 30 || len(calls) != 30 {
+		t.Fatalf("expected 30 sent, got %+v", stats)
+	}
+
+	first := calls[0]
+	if first.UserAgent != "ua" || first.Options.ClientIP != "203.0.113.7" || first.Event.Payload.Timestamp == 0 {
+		t.Errorf("record not forwarded: %+v", first)
+	}
+
+	// Within a session, URLs (and timestamps) must increase.
+	last := map[int64]int64{}
+	for _, c := range calls {
+		var i int64
+		fmt.Sscanf(c.Event.Payload.URL, "/%d", &i)
+		session := i % 4
+		if prev, ok := last[session]; ok && c.Event.Payload.Timestamp <= prev {
+			t.Fatalf("session %d out of order at %s", session, c.Event.Payload.URL)
+		}
+		last[session] = c.Event.Payload.Timestamp
+	}
+}
+
+func TestRunner_ResumesFromCheckpoint(t *testing.T) {
+	cp := backfill.NewFileCheckpoint(filepath.Join(t.TempDir(), "import.checkpoint"))
+	input := jsonl(20, 3)
+
+	failing := failOn("/12")
+	_, err := backfill.NewRunner(failing, backfill.WithWorkers(2), backfill.WithCheckpoint(cp, 1)).
+		Run(context.Background(), backfill.NewJSONLReader(strings.NewReader(input)))
+	if err == nil {
+		t.Fatal("expected the run to stop on the failing record")
+	}
+
+	position, err := cp.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position > 12 {
+		t.Fatalf("checkpoint %d is past the failed record", position)
+	}
+
+	recovered := &publictest.Public{}
+	stats, err := backfill.NewRunner(recovered, backfill.WithWorkers(2), backfill.WithCheckpoint(cp, 1)).
+		Run(context.Background(), backfill.NewJSONLReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Skipped != position || stats.Sent != 20-position {
+		t.Errorf("expected %d skipped and %d sent, got %+v", position, 20-position, stats)
+	}
+
+	all := map[string]bool{}
+	for _, u := range append(failing.URLs(), recovered.URLs()...) {
+		all[u] = true
+	}
+	if len(all) != 20 {
+		t.Errorf("expected every record to be imported, got %d", len(all))
+	}
+	if position, _ = cp.Load(context.Background()); position != 20 {
+		t.Errorf("expected final checkpoint 20, got %d", position)
+	}
+}
+
+func TestRunner_ErrorHandlerSkips(t *testing.T) {
+	public := failOn("/3")
+	var skipped []int64
+	runner := backfill.NewRunner(public, backfill.WithErrorHandler(func(index int64, rec backfill.Record, err error) error {
+		skipped = append(skipped, index)
+		return nil
+	}))
+
+	stats, err := runner.Run(context.Background(), backfill.NewJSONLReader(strings.NewReader(jsonl(5, 1))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Sent != 4 || stats.Failed != 1 || fmt.Sprint(skipped) != "[3]" {
+		t.Errorf("unexpected stats %+v, skipped %v", stats, skipped)
+	}
+}
+
+func TestRunner_Throttles(t *testing.T) {
+	runner := backfill.NewRunner(&publictest.Public{}, backfill.WithRate(200))
+
+	start := time.Now()
+	if _, err := runner.Run(context.Background(), backfill.NewJSONLReader(strings.NewReader(jsonl(11, 2)))); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("expected at least 50ms for 11 records at 200/s, took %s", elapsed)
+	}
+}
+
+func TestJSONLReader_ReportsLine(t *testing.T) {
+	_, err := backfill.NewJSONLReader(strings.NewReader("\n{oops}\n")).Read()
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error on line 2, got %v", err)
+	}
+}
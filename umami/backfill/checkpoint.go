@@ -0,0 +1,58 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Checkpoint persists the import progress: the number of records, from the start of the
+// input, that have all been handled.
+type Checkpoint interface {
+	Load(ctx context.Context) (int64, error)
+	Save(ctx context.Context, position int64) error
+}
+
+// FileCheckpoint stores the progress in a file, replaced atomically on every save.
+type FileCheckpoint struct {
+	path string
+}
+
+func NewFileCheckpoint(path string) *FileCheckpoint {
+	return &FileCheckpoint{path: path}
+}
+
+// Load returns the saved position, or 0 when nothing was saved yet.
+func (c *FileCheckpoint) Load(context.Context) (int64, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read checkpoint: %w", err)
+	}
+
+	position, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("decode checkpoint: %w", err)
+	}
+	return position, nil
+}
+
+func (c *FileCheckpoint) Save(_ context.Context, position int64) error {
+	tmp := c.path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return fmt.Errorf("create checkpoint dir: %w", err)
+	}
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(position, 10)), 0o600); err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("replace checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package backfill
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"io"
+	"time"
+)
+
+// Record is a historical event to import.
+type Record struct {
+	// Time is when the event happened; it is sent as the payload timestamp unless zero.
+	Time      time.Time `json:"time"`
+	UserAgent string    `json:"userAgent"`
+	IP        string    `json:"ip,omitempty"`
+	// Session groups the records whose order must be preserved. It defaults to the
+	// user agent and IP, from which Umami derives sessions.
+	Session string                 `json:"session,omitempty"`
+	Event   types.SendEventRequest `json:"event"`
+}
+
+func (r Record) sessionKey() string {
+	if r.Session != "" {
+		return r.Session
+	}
+	return r.UserAgent + "|" + r.IP
+}
+
+// Reader streams records, returning io.EOF after the last one.
+type Reader interface {
+	Read() (Record, error)
+}
+
+// ReaderFunc adapts a function to the Reader interface.
+type ReaderFunc func() (Record, error)
+
+func (f ReaderFunc) Read() (Record, error) {
+	return f()
+}
+
+// NewJSONLReader reads one JSON-encoded Record per line, skipping blank lines.
+func NewJSONLReader(r io.Reader) Reader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	line := 0
+
+	return ReaderFunc(func() (Record, error) {
+		for sc.Scan() {
+			line++
+			if len(sc.Bytes()) == 0 {
+				continue
+			}
+			var rec Record
+			if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+				return Record{}, fmt.Errorf("line %d: %w", line, err)
+			}
+			return rec, nil
+		}
+		if err := sc.Err(); err != nil {
+			return Record{}, err
+		}
+		return Record{}, io.EOF
+	})
+}
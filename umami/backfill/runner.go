@@ -0,0 +1,255 @@
+package backfill
+
+import (
+	"context"
+	"errors"
+	"github.com/AdamShannag/umami-client/umami/api"
+	"hash/fnv"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the counters of a run.
+type Stats struct {
+	Sent    int64 // Records sent
+	Failed  int64 // Records whose send failed and were skipped by the error handler
+	Skipped int64 // Records before the checkpoint, already imported by a previous run
+}
+
+// Option configures a Runner.
+type Option func(*Runner)
+
+// WithWorkers sets the number of concurrent senders (default 4). Records of one session always
+// go through the same worker, so they are sent in input order.
+func WithWorkers(n int) Option {
+	return func(r *Runner) {
+		if n > 0 {
+			r.workers = n
+		}
+	}
+}
+
+// WithRate caps the number of records sent per second; unlimited by default.
+func WithRate(perSecond float64) Option {
+	return func(r *Runner) {
+		r.rate = perSecond
+	}
+}
+
+// WithCheckpoint saves the progress to cp every n handled records and at the end of the run,
+// and resumes from the saved position.
+func WithCheckpoint(cp Checkpoint, every int64) Option {
+	return func(r *Runner) {
+		r.checkpoint, r.every = cp, max(every, 1)
+	}
+}
+
+// WithErrorHandler sets the function deciding what happens when a record cannot be sent:
+// returning nil skips it, returning an error stops the run. By default the run stops.
+func WithErrorHandler(fn func(index int64, rec Record, err error) error) Option {
+	return func(r *Runner) {
+		r.onError = fn
+	}
+}
+
+// Runner imports historical events through Public.Send, setting each payload timestamp
+// from its record.
+type Runner struct {
+	public     api.Public
+	workers    int
+	rate       float64
+	checkpoint Checkpoint
+	every      int64
+	onError    func(index int64, rec Record, err error) error
+}
+
+func NewRunner(public api.Public, opts ...Option) *Runner {
+	r := &Runner{public: public, workers: 4}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+type job struct {
+	index int64
+	rec   Record
+}
+
+// Run sends the records of src until it returns io.EOF, ctx is done or a record fails. Records are
+// numbered from 0 in input order; on resume, those before the checkpoint are skipped. Records sent
+// after the last saved checkpoint may be sent again by the next run.
+func (r *Runner) Run(ctx context.Context, src Reader) (Stats, error) {
+	var stats Stats
+
+	var start int64
+	if r.checkpoint != nil {
+		var err error
+		if start, err = r.checkpoint.Load(ctx); err != nil {
+			return stats, err
+		}
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var sent, failed atomic.Int64
+	done := make(chan int64, r.workers)
+	queues := make([]chan job, r.workers)
+
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan job, 64)
+		wg.Add(1)
+		go func(queue <-chan job) {
+			defer wg.Done()
+			for j := range queue {
+				// Once the run is stopping, the remaining records are left for the next one.
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := r.send(ctx, j.rec); err != nil {
+					if ctx.Err() != nil {
+						continue
+					}
+					if err = r.handle(j, err); err != nil {
+						cancel(err)
+						continue
+					}
+					failed.Add(1)
+				} else {
+					sent.Add(1)
+				}
+				done <- j.index
+			}
+		}(queues[i])
+	}
+
+	saved := make(chan error, 1)
+	go func() {
+		saved <- r.track(context.WithoutCancel(ctx), start, done)
+	}()
+
+	readErr := r.dispatch(ctx, src, start, queues, &stats)
+	for _, q := range queues {
+		close(q)
+	}
+	wg.Wait()
+	close(done)
+	saveErr := <-saved
+
+	stats.Sent, stats.Failed = sent.Load(), failed.Load()
+	if readErr != nil {
+		cancel(readErr)
+	}
+	if err := context.Cause(ctx); err != nil {
+		return stats, err
+	}
+	return stats, saveErr
+}
+
+// dispatch reads the records and hands them to the worker of their session.
+func (r *Runner) dispatch(ctx context.Context, src Reader, start int64, queues []chan job, stats *Stats) error {
+	var interval time.Duration
+	if r.rate > 0 {
+		interval = time.Duration(float64(time.Second) / r.rate)
+	}
+	next := time.Now()
+
+	for index := int64(0); ; index++ {
+		rec, err := src.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if index < start {
+			stats.Skipped++
+			continue
+		}
+
+		if interval > 0 {
+			if wait := time.Until(next); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil
+				case <-timer.C:
+				}
+			}
+			if now := time.Now(); now.After(next) {
+				next = now
+			}
+			next = next.Add(interval)
+		}
+
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(rec.sessionKey()))
+		select {
+		case queues[h.Sum32()%uint32(len(queues))] <- job{index: index, rec: rec}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (r *Runner) send(ctx context.Context, rec Record) error {
+	event := rec.Event
+	if !rec.Time.IsZero() {
+		event.Payload.Timestamp = rec.Time.Unix()
+	}
+
+	var opts []api.SendOption
+	if rec.IP != "" {
+		opts = append(opts, api.WithClientIP(rec.IP))
+	}
+	_, err := r.public.Send(ctx, rec.UserAgent, event, opts...)
+	return err
+}
+
+func (r *Runner) handle(j job, err error) error {
+	if r.onError == nil {
+		return err
+	}
+	return r.onError(j.index, j.rec, err)
+}
+
+// track advances the checkpoint to the first record not handled yet. Records finish out of order
+// across workers, so the ones beyond that position are remembered until it catches up.
+func (r *Runner) track(ctx context.Context, position int64, done <-chan int64) error {
+	pending := map[int64]struct{}{}
+	lastSaved := position
+
+	var saveErr error
+	save := func() {
+		if r.checkpoint == nil || position == lastSaved {
+			return
+		}
+		if err := r.checkpoint.Save(ctx, position); err != nil {
+			saveErr = err
+			return
+		}
+		lastSaved = position
+	}
+
+	for index := range done {
+		pending[index] = struct{}{}
+		for {
+			if _, ok := pending[position]; !ok {
+				break
+			}
+			delete(pending, position)
+			position++
+		}
+		if position-lastSaved >= r.every {
+			save()
+		}
+	}
+	save()
+	return saveErr
+}
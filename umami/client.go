@@ -2,27 +2,47 @@ package umami
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/AdamShannag/umami-client/umami/api"
 	"github.com/AdamShannag/umami-client/umami/auth"
+	"github.com/AdamShannag/umami-client/umami/auth/token"
 	"github.com/AdamShannag/umami-client/umami/request"
-	"log"
+	"github.com/AdamShannag/umami-client/umami/scrub"
+	"github.com/AdamShannag/umami-client/umami/validate"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const defaultTokenExpiry = 24 * time.Hour
 
+// defaultBatchSize is the number of events sent per /api/batch request.
+const defaultBatchSize = 100
+
 // Option represents a functional client option used during initialization.
-type Option func(*client)
+// An option returning an error aborts New.
+type Option func(*client) error
 
 // Client Umami API client.
 type Client interface {
-	// GetToken returns the current auth token and its remaining TTL.
-	GetToken(string, string) (string, time.Duration, error)
+	// GetToken logs in and returns the auth token and its remaining TTL.
+	GetToken(ctx context.Context, username, password string) (string, time.Duration, error)
 
 	// User returns the User API interface.
 	User() api.User
 
+	// Admin returns the Admin API interface.
+	Admin() api.Admin
+
+	// Me returns the Me API interface for the authenticated user.
+	Me() api.Me
+
 	// Team returns the Team API interface.
 	Team() api.Team
 
@@ -38,13 +58,24 @@ type Client interface {
 	// WebsiteStats returns the WebsiteStats API interface.
 	WebsiteStats() api.WebsiteStats
 
+	// Realtime returns the Realtime API interface.
+	Realtime() api.Realtime
+
 	// Public returns the Public API interface.
 	Public() api.Public
 
 	// Report returns the Report API interface.
 	Report() api.Report
 
-	// Close shuts down background token refreshes.
+	// NextTokenRefresh returns when the token will next be refreshed; ok is false
+	// unless the client was created with WithTokenRefresh.
+	NextTokenRefresh() (next time.Time, ok bool)
+
+	// Watch polls the realtime activity of a website every interval until ctx is done or the
+	// client is closed, then closes the returned channel. See WatchUpdate.
+	Watch(ctx context.Context, websiteID string, interval time.Duration) <-chan WatchUpdate
+
+	// Close shuts down background token refreshes and stops every Watch.
 	Close()
 }
 
@@ -52,73 +83,307 @@ type Client interface {
 type client struct {
 	hostURL     string
 	tokenExpiry time.Duration
+	lazyLogin   bool
+	refreshOpts []token.Option
+	refresher   *auth.TokenRefresherAuth
+	tokenStore  auth.TokenStore
+	batchSize   int
+	validator   validate.Validator
+	scrubber    *scrub.Scrubber
+
+	logger    *slog.Logger
+	logLevels request.LogLevels
+	// setup holds option steps that must run once every option has been applied, such as logging in.
+	setup []func(context.Context) error
 
 	cancel     context.CancelFunc
+	closed     chan struct{}
+	closeOnce  sync.Once
 	httpClient request.Client
 }
 
+// New creates a client for the Umami instance at hostURL. Options are applied in order, then
+// any login they require is performed with ctx; the first failure is returned.
+func New(ctx context.Context, hostURL string, opts ...Option) (Client, error) {
+	c, err := newClient(ctx, hostURL, opts...)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// exit terminates the process when NewClient fails; tests replace it.
+var exit = os.Exit
+
+// NewClient creates a client like New, but logs the error and exits the process when
+// initialization fails, e.g. when the login of WithSingleToken is rejected. Use New to handle
+// the error instead.
 func NewClient(hostURL string, opts ...Option) Client {
+	c, err := newClient(context.Background(), hostURL, opts...)
+	if err != nil {
+		c.log().Error("umami: client initialization failed", slog.Any("error", err))
+		c.Close()
+		exit(1)
+	}
+	return c
+}
+
+func newClient(ctx context.Context, hostURL string, opts ...Option) (*client, error) {
 	c := &client{
-		hostURL:     hostURL,
+		hostURL:     strings.TrimSuffix(hostURL, "/"),
 		tokenExpiry: defaultTokenExpiry,
+		logLevels:   request.DefaultLogLevels(),
 		httpClient:  request.NewClient(),
+		batchSize:   defaultBatchSize,
+		validator:   validate.Default(),
+		closed:      make(chan struct{}),
+	}
+
+	if u, err := url.Parse(c.hostURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return c, fmt.Errorf("invalid host url %q", hostURL)
 	}
 
 	for _, opt := range opts {
-		opt(c)
+		if err := opt(c); err != nil {
+			return c, err
+		}
 	}
 
-	return c
+	if c.logger != nil {
+		c.httpClient.WithLogger(c.logger, c.logLevels)
+	}
+
+	for _, fn := range c.setup {
+		if err := fn(ctx); err != nil {
+			return c, err
+		}
+	}
+
+	return c, nil
 }
 
 func WithApiKey(apiKey string) Option {
-	return func(c *client) {
+	return func(c *client) error {
 		c.httpClient.WithAuth(auth.NewApiKeyAuth(apiKey))
+		return nil
 	}
 }
 
+// WithSingleToken logs in once and uses the returned token for every request, logging in
+// again if the server rejects it. The login happens during New, or on the first request
+// when WithLazyLogin is set.
 func WithSingleToken(username, password string) Option {
-	return func(c *client) {
-		getToken, _, err := c.GetToken(username, password)
-		if err != nil {
-			log.Fatal(err)
-		}
+	return func(c *client) error {
+		c.setup = append(c.setup, func(ctx context.Context) error {
+			loginFn := c.loginFunc(username, password)
+			login := func(ctx context.Context) (string, error) {
+				accessToken, _, err := loginFn(ctx)
+				return accessToken, err
+			}
+
+			if c.lazyLogin {
+				c.httpClient.WithAuth(auth.NewLazyTokenAuth(login))
+				return nil
+			}
+
+			getToken, err := login(ctx)
+			if err != nil {
+				return fmt.Errorf("login: %w", err)
+			}
 
-		c.httpClient.WithAuth(auth.NewSingleTokenAuth(getToken))
+			c.httpClient.WithAuth(auth.NewRenewableTokenAuth(getToken, login))
+			return nil
+		})
+		return nil
 	}
 }
 
+// WithTokenRefresh logs in during New and keeps the token fresh in the background until Close.
+// A failed background refresh is retried while the current token is still used.
 func WithTokenRefresh(username, password string) Option {
-	return func(c *client) {
-		ctx, cancel := context.WithCancel(context.Background())
-		c.cancel = cancel
-		c.httpClient.WithAuth(auth.NewTokenRefresherAuth(ctx, func() (string, time.Duration, error) {
-			return c.GetToken(username, password)
-		}))
+	return func(c *client) error {
+		c.setup = append(c.setup, func(setupCtx context.Context) error {
+			ctx, cancel := context.WithCancel(context.Background())
+			c.cancel = cancel
+			login := c.loginFunc(username, password)
+			c.refresher = auth.NewTokenRefresherAuth(ctx, func() (string, time.Duration, error) {
+				return login(ctx)
+			}, c.refreshOpts...).(*auth.TokenRefresherAuth)
+			c.httpClient.WithAuth(c.refresher)
+
+			if _, err := c.refresher.Get(setupCtx); err != nil {
+				return fmt.Errorf("login: %w", err)
+			}
+			return nil
+		})
+		return nil
+	}
+}
+
+// WithTokenStore makes WithSingleToken and WithTokenRefresh reuse a still-valid token saved by a
+// previous run, and save every new token they obtain.
+func WithTokenStore(store auth.TokenStore) Option {
+	return func(c *client) error {
+		c.tokenStore = store
+		return nil
+	}
+}
+
+// WithLazyLogin defers the WithSingleToken login until the first authenticated request,
+// so the client can be created while Umami is unreachable.
+func WithLazyLogin() Option {
+	return func(c *client) error {
+		c.lazyLogin = true
+		return nil
+	}
+}
+
+// WithTokenRefreshFraction makes WithTokenRefresh renew the token once the given fraction (0-1]
+// of its lifetime has elapsed (default: 0.8).
+func WithTokenRefreshFraction(f float64) Option {
+	return func(c *client) error {
+		if f <= 0 || f > 1 {
+			return fmt.Errorf("token refresh fraction must be in (0, 1], got %v", f)
+		}
+		c.refreshOpts = append(c.refreshOpts, token.WithRefreshFraction(f))
+		return nil
 	}
 }
 
+// WithTokenExpiry sets the token lifetime assumed for opaque tokens. The exp claim is used instead
+// when Umami returns a JWT.
 func WithTokenExpiry(d time.Duration) Option {
-	return func(c *client) {
+	return func(c *client) error {
+		if d <= 0 {
+			return fmt.Errorf("token expiry must be positive, got %s", d)
+		}
 		c.tokenExpiry = d
+		return nil
+	}
+}
+
+// WithBatchSize sets the maximum number of events SendBatch posts per request (default 100).
+// Keep it within the batch size accepted by the Umami server.
+func WithBatchSize(n int) Option {
+	return func(c *client) error {
+		if n < 1 {
+			return fmt.Errorf("batch size must be positive, got %d", n)
+		}
+		c.batchSize = n
+		return nil
+	}
+}
+
+// WithScrubber removes personal data from the URL, referrer, title and data of tracking payloads
+// before they are validated and sent, e.g. with scrub.Default().
+func WithScrubber(s *scrub.Scrubber) Option {
+	return func(c *client) error {
+		c.scrubber = s
+		return nil
+	}
+}
+
+// WithValidator sets how tracking payloads are checked against Umami's limits before being sent.
+// By default they are truncated to validate.DefaultLimits; use validate.PassThrough to disable checks.
+func WithValidator(v validate.Validator) Option {
+	return func(c *client) error {
+		c.validator = v
+		return nil
 	}
 }
 
 func WithHttpClient(httpClient *http.Client) Option {
-	return func(c *client) {
+	return func(c *client) error {
 		c.httpClient.WithHttpClient(httpClient)
+		return nil
+	}
+}
+
+// WithRetry retries failed GET requests and report queries according to the given policy.
+func WithRetry(policy request.RetryPolicy) Option {
+	return func(c *client) error {
+		c.httpClient.WithRetry(policy)
+		return nil
+	}
+}
+
+// WithRateLimit limits requests to rps per second with bursts of up to burst requests.
+// Without groups the limit applies to each endpoint group separately, so tracking calls
+// to /api/send are never queued behind report queries.
+func WithRateLimit(rps float64, burst int, groups ...request.Group) Option {
+	return func(c *client) error {
+		c.httpClient.WithRateLimit(rps, burst, groups...)
+		return nil
+	}
+}
+
+// WithMaxInFlight caps the number of concurrent requests, per endpoint group.
+func WithMaxInFlight(n int, groups ...request.Group) Option {
+	return func(c *client) error {
+		c.httpClient.WithMaxInFlight(n, groups...)
+		return nil
+	}
+}
+
+// WithMiddleware registers interceptors wrapping every request sent by the client,
+// e.g. to add headers, cache responses or inject faults.
+func WithMiddleware(interceptors ...request.Interceptor) Option {
+	return func(c *client) error {
+		c.httpClient.WithMiddleware(interceptors...)
+		return nil
+	}
+}
+
+// WithLogger logs every request's method, endpoint, query, status, latency and retry attempt.
+// Credentials in auth headers and login payloads are redacted.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *client) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// WithLogLevels overrides the levels used by WithLogger (default: request.DefaultLogLevels).
+func WithLogLevels(levels request.LogLevels) Option {
+	return func(c *client) error {
+		c.logLevels = levels
+		return nil
+	}
+}
+
+func (c *client) NextTokenRefresh() (time.Time, bool) {
+	if c.refresher == nil {
+		return time.Time{}, false
 	}
+	next := c.refresher.NextRefresh()
+	return next, !next.IsZero()
 }
 
 func (c *client) Close() {
+	c.closeOnce.Do(func() { close(c.closed) })
 	if c.cancel != nil {
 		c.cancel()
 	}
 }
 
+// log returns the configured logger, falling back to the slog default logger.
+func (c *client) log() *slog.Logger {
+	if c.logger != nil {
+		return c.logger
+	}
+	return slog.Default()
+}
+
 func (c *client) User() api.User {
 	return c
 }
+func (c *client) Admin() api.Admin {
+	return c
+}
+func (c *client) Me() api.Me {
+	return c
+}
 func (c *client) Team() api.Team {
 	return c
 }
@@ -134,9 +399,49 @@ func (c *client) Website() api.Website {
 func (c *client) WebsiteStats() api.WebsiteStats {
 	return c
 }
+func (c *client) Realtime() api.Realtime {
+	return c
+}
 func (c *client) Public() api.Public { return c }
 func (c *client) Report() api.Report { return c }
 
+// minStoredTokenTTL is the validity a stored token must have left to be reused.
+const minStoredTokenTTL = time.Minute
+
+// loginFunc returns a login function for the token-based auth options. Only its first call may
+// reuse a token from the token store: later calls happen because the token expired or was
+// rejected, so they always log in and save the new token.
+func (c *client) loginFunc(username, password string) func(context.Context) (string, time.Duration, error) {
+	key := auth.StoreKey(c.hostURL, username)
+	var loaded atomic.Bool
+
+	return func(ctx context.Context) (string, time.Duration, error) {
+		if c.tokenStore == nil {
+			return c.GetToken(ctx, username, password)
+		}
+
+		if !loaded.Swap(true) {
+			stored, expiresAt, err := c.tokenStore.Load(ctx, key)
+			if ttl := time.Until(expiresAt); err == nil && ttl > minStoredTokenTTL {
+				return stored, ttl, nil
+			}
+			if err != nil && !errors.Is(err, auth.ErrTokenNotFound) {
+				c.log().Warn("umami: load stored token failed", slog.Any("error", err))
+			}
+		}
+
+		accessToken, ttl, err := c.GetToken(ctx, username, password)
+		if err != nil {
+			return "", 0, err
+		}
+
+		if err = c.tokenStore.Save(ctx, key, accessToken, time.Now().Add(ttl)); err != nil {
+			c.log().Warn("umami: save token failed", slog.Any("error", err))
+		}
+		return accessToken, ttl, nil
+	}
+}
+
 func (c *client) getRequest(ctx context.Context, endpoint string, query map[string]string, v any) error {
 	return c.httpClient.Send(ctx, request.Request{
 		Method:   http.MethodGet,
@@ -157,6 +462,18 @@ func (c *client) postRequest(ctx context.Context, endpoint string, payload any,
 	}, v)
 }
 
+// queryRequest sends a POST that only reads data, such as the report endpoints, so it may be retried.
+func (c *client) queryRequest(ctx context.Context, endpoint string, payload any, v any) error {
+	return c.httpClient.Send(ctx, request.Request{
+		Method:     http.MethodPost,
+		Endpoint:   endpoint,
+		Headers:    nil,
+		Query:      nil,
+		Payload:    payload,
+		Idempotent: true,
+	}, v)
+}
+
 func (c *client) deleteRequest(ctx context.Context, endpoint string) error {
 	return c.httpClient.Send(ctx, request.Request{
 		Method:   http.MethodDelete,
@@ -4,9 +4,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AdamShannag/umami-client/umami/api"
+	"github.com/AdamShannag/umami-client/umami/auth"
+	"github.com/AdamShannag/umami-client/umami/request"
+	"github.com/AdamShannag/umami-client/umami/scrub"
 	"github.com/AdamShannag/umami-client/umami/types"
+	"github.com/AdamShannag/umami-client/umami/validate"
 	"io"
+	"log/slog"
 	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -19,6 +31,12 @@ func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error)
 	return m.fn(req), nil
 }
 
+// testEvent is a tracking event passing the default validation.
+var testEvent = types.SendEventRequest{
+	Type:    "event",
+	Payload: types.SendEventPayload{Website: "3fa85f64-5717-4562-b3fc-2c963f66afa6", URL: "/"},
+}
+
 func newMockClient(fn func(*http.Request) *http.Response) Client {
 	return NewClient("https://example.com",
 		WithApiKey("test"),
@@ -853,7 +871,51 @@ func TestClient_Send(t *testing.T) {
 		return mockJSONResp([]byte(`{}`))
 	})
 
-	err := c.Public().Send(context.Background(), "TestAgent", types.SendEventRequest{})
+	_, err := c.Public().Send(context.Background(), "TestAgent", testEvent)
+	assertNil(t, err)
+}
+
+func TestClient_SendResponseBodies(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want types.SendResponse
+	}{
+		{"json", `{"cache":"tok","sessionId":"s1","visitId":"v1"}`, types.SendResponse{Cache: "tok", SessionID: "s1", VisitID: "v1"}},
+		{"empty", ``, types.SendResponse{}},
+		{"plain cache token", "eyJhbGciOiJIUzI1NiJ9.e30.sig\n", types.SendResponse{Cache: "eyJhbGciOiJIUzI1NiJ9.e30.sig"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newMockClient(func(r *http.Request) *http.Response {
+				return mockJSONResp([]byte(tt.body))
+			})
+
+			got, err := c.Public().Send(context.Background(), "TestAgent", testEvent)
+			assertNil(t, err)
+			assertEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestClient_SendWithOptions(t *testing.T) {
+	c := newMockClient(func(r *http.Request) *http.Response {
+		assertEqual(t, r.Header.Get("X-Forwarded-For"), "203.0.113.7")
+		assertEqual(t, r.Header.Get("X-Real-IP"), "203.0.113.7")
+		assertEqual(t, r.Header.Get("Accept-Language"), "de-DE")
+		assertEqual(t, r.Header.Get("CF-Connecting-IP"), "203.0.113.7")
+
+		var body types.SendEventRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		assertEqual(t, body.Payload.IP, "203.0.113.7")
+		return mockJSONResp([]byte(`{}`))
+	})
+
+	_, err := c.Public().Send(context.Background(), "TestAgent", testEvent,
+		api.WithClientIP("203.0.113.7"),
+		api.WithAcceptLanguage("de-DE"),
+		api.WithHeader("CF-Connecting-IP", "203.0.113.7"),
+	)
 	assertNil(t, err)
 }
 
@@ -996,7 +1058,7 @@ func TestClient_GetRevenue(t *testing.T) {
 
 	c := newMockClient(func(r *http.Request) *http.Response {
 		assertEqual(t, r.Method, http.MethodPost)
-		assertEqual(t, r.URL.Path, "/api/reports/attribution")
+		assertEqual(t, r.URL.Path, "/api/reports/revenue")
 		return mockJSONResp(b)
 	})
 
@@ -1027,3 +1089,625 @@ func TestClient_GetAttribution(t *testing.T) {
 	assertEqual(t, got.Total.Visitors, 100)
 	assertEqual(t, got.Total.Visits, 200)
 }
+
+func TestClient_APIError(t *testing.T) {
+	c := newMockClient(func(r *http.Request) *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusUnauthorized,
+			Body:       io.NopCloser(bytes.NewReader([]byte("Unauthorized"))),
+			Header:     make(http.Header),
+		}
+	})
+
+	_, err := c.Website().GetWebsite(context.Background(), "site123")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T", err)
+	}
+	assertEqual(t, apiErr.Endpoint, "https://example.com/api/websites/site123")
+	assertEqual(t, apiErr.Message, "Unauthorized")
+}
+
+func TestClient_RetryReportQuery(t *testing.T) {
+	calls := 0
+	c := NewClient("https://example.com",
+		WithApiKey("test"),
+		WithRetry(request.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(r *http.Request) *http.Response {
+			calls++
+			if calls == 1 {
+				return &http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(bytes.NewReader(nil)), Header: make(http.Header)}
+			}
+			return mockJSONResp([]byte(`[]`))
+		}}}),
+	)
+
+	_, err := c.Report().GetFunnel(context.Background(), types.ReportFunnelRequest{})
+	assertNil(t, err)
+	assertEqual(t, calls, 2)
+}
+
+func TestNewClient_LoginFailureExits(t *testing.T) {
+	code := -1
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+
+	var buf bytes.Buffer
+	NewClient("https://example.com",
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+		WithSingleToken("admin", "secret-password"),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(r *http.Request) *http.Response {
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: io.NopCloser(bytes.NewReader(nil)), Header: make(http.Header)}
+		}}}),
+	)
+
+	assertEqual(t, code, 1)
+	if !strings.Contains(buf.String(), "initialization failed") {
+		t.Errorf("expected login failure to be logged, got %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "secret-password") {
+		t.Errorf("password leaked into logs: %q", buf.String())
+	}
+}
+
+func TestNew_TokenRefreshLoginFailure(t *testing.T) {
+	_, err := New(context.Background(), "https://example.com",
+		WithTokenRefresh("admin", "password"),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(r *http.Request) *http.Response {
+			return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(bytes.NewReader(nil)), Header: make(http.Header)}
+		}}}),
+	)
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestNew_LoginFailure(t *testing.T) {
+	_, err := New(context.Background(), "https://example.com",
+		WithSingleToken("admin", "password"),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(r *http.Request) *http.Response {
+			return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(bytes.NewReader(nil)), Header: make(http.Header)}
+		}}}),
+	)
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestNew_InvalidOptions(t *testing.T) {
+	if _, err := New(context.Background(), "not a url"); err == nil {
+		t.Errorf("expected error for invalid host url")
+	}
+	if _, err := New(context.Background(), "https://example.com", WithTokenExpiry(0)); err == nil {
+		t.Errorf("expected error for non-positive token expiry")
+	}
+}
+
+func TestNew_LazyLogin(t *testing.T) {
+	logins := 0
+	c, err := New(context.Background(), "https://example.com",
+		WithSingleToken("admin", "password"),
+		WithLazyLogin(),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(r *http.Request) *http.Response {
+			if r.URL.Path == "/api/auth/login" {
+				logins++
+				return mockJSONResp([]byte(`{"token":"lazy-token"}`))
+			}
+			assertEqual(t, r.Header.Get("Authorization"), "Bearer lazy-token")
+			return mockJSONResp([]byte(`{"id":"site123"}`))
+		}}}),
+	)
+	assertNil(t, err)
+	assertEqual(t, logins, 0)
+
+	for i := 0; i < 2; i++ {
+		_, err = c.Website().GetWebsite(context.Background(), "site123")
+		assertNil(t, err)
+	}
+	assertEqual(t, logins, 1)
+}
+
+func TestClient_SingleTokenReauthenticates(t *testing.T) {
+	var mu sync.Mutex
+	logins := 0
+	c, err := New(context.Background(), "https://example.com",
+		WithSingleToken("admin", "password"),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(r *http.Request) *http.Response {
+			mu.Lock()
+			defer mu.Unlock()
+			if r.URL.Path == "/api/auth/login" {
+				logins++
+				return mockJSONResp([]byte(fmt.Sprintf(`{"token":"token-%d"}`, logins)))
+			}
+			if r.Header.Get("Authorization") != "Bearer token-2" {
+				return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(bytes.NewReader(nil)), Header: make(http.Header)}
+			}
+			return mockJSONResp([]byte(`{"id":"site123"}`))
+		}}}),
+	)
+	assertNil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Website().GetWebsite(context.Background(), "site123"); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assertEqual(t, logins, 2)
+}
+
+func TestClient_TokenStoreReusesValidToken(t *testing.T) {
+	ctx := context.Background()
+	store := auth.NewMemoryTokenStore()
+	assertNil(t, store.Save(ctx, auth.StoreKey("https://example.com", "admin"), "stored-token", time.Now().Add(time.Hour)))
+
+	logins := 0
+	c, err := New(ctx, "https://example.com",
+		WithTokenStore(store),
+		WithSingleToken("admin", "password"),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(r *http.Request) *http.Response {
+			if r.URL.Path == "/api/auth/login" {
+				logins++
+				return mockJSONResp([]byte(`{"token":"new-token"}`))
+			}
+			assertEqual(t, r.Header.Get("Authorization"), "Bearer stored-token")
+			return mockJSONResp([]byte(`{"id":"site123"}`))
+		}}}),
+	)
+	assertNil(t, err)
+
+	_, err = c.Website().GetWebsite(ctx, "site123")
+	assertNil(t, err)
+	assertEqual(t, logins, 0)
+}
+
+func TestClient_TokenStoreSavesNewToken(t *testing.T) {
+	ctx := context.Background()
+	store := auth.NewMemoryTokenStore()
+	key := auth.StoreKey("https://example.com", "admin")
+	assertNil(t, store.Save(ctx, key, "expired-token", time.Now().Add(-time.Hour)))
+
+	_, err := New(ctx, "https://example.com",
+		WithTokenStore(store),
+		WithSingleToken("admin", "password"),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(r *http.Request) *http.Response {
+			return mockJSONResp([]byte(`{"token":"new-token"}`))
+		}}}),
+	)
+	assertNil(t, err)
+
+	token, exp, err := store.Load(ctx, key)
+	assertNil(t, err)
+	assertEqual(t, token, "new-token")
+	if time.Until(exp) < 23*time.Hour {
+		t.Errorf("expected saved token to expire after the default expiry, got %v", exp)
+	}
+}
+
+func TestMe_GetMe(t *testing.T) {
+	expected := types.Me{Token: "tok", User: types.CurrentUser{ID: "u1", Username: "me", IsAdmin: true}}
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodGet || req.URL.Path != "/api/me" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		b, _ := json.Marshal(expected)
+		return mockJSONResp(b)
+	})
+
+	got, err := mock.Me().GetMe(context.Background())
+	assertNil(t, err)
+	assertEqual(t, got.User.Username, "me")
+	assertEqual(t, got.User.IsAdmin, true)
+}
+
+func TestMe_GetMyWebsites(t *testing.T) {
+	expected := types.UserWebsites{Data: []types.UserWebsite{{ID: "w1"}}}
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodGet || req.URL.Path != "/api/me/websites" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		assertEqual(t, req.URL.Query().Get("page"), "2")
+		b, _ := json.Marshal(expected)
+		return mockJSONResp(b)
+	})
+
+	got, err := mock.Me().GetMyWebsites(context.Background(), types.ListQueryParams{Page: "2"})
+	assertNil(t, err)
+	assertEqual(t, got.Data[0].ID, "w1")
+}
+
+func TestMe_GetMyTeams(t *testing.T) {
+	expected := types.UserTeams{Data: []types.UserTeam{{ID: "t1"}}}
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodGet || req.URL.Path != "/api/me/teams" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		assertEqual(t, req.URL.Query().Get("pageSize"), "50")
+		b, _ := json.Marshal(expected)
+		return mockJSONResp(b)
+	})
+
+	got, err := mock.Me().GetMyTeams(context.Background(), types.ListQueryParams{PageSize: "50"})
+	assertNil(t, err)
+	assertEqual(t, got.Data[0].ID, "t1")
+}
+
+func TestMe_UpdateMyPassword(t *testing.T) {
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodPost || req.URL.Path != "/api/me/password" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		var body types.UpdatePasswordRequest
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		assertEqual(t, body.CurrentPassword, "old")
+		assertEqual(t, body.NewPassword, "new")
+		return mockJSONResp([]byte(`{"id":"u1","username":"me"}`))
+	})
+
+	got, err := mock.Me().UpdateMyPassword(context.Background(), types.UpdatePasswordRequest{CurrentPassword: "old", NewPassword: "new"})
+	assertNil(t, err)
+	assertEqual(t, got.ID, "u1")
+}
+
+func TestMe_VerifyToken(t *testing.T) {
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodPost || req.URL.Path != "/api/auth/verify" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		return mockJSONResp([]byte(`{"id":"u1","username":"me","role":"user"}`))
+	})
+
+	got, err := mock.Me().VerifyToken(context.Background())
+	assertNil(t, err)
+	assertEqual(t, got.Role, "user")
+}
+
+func TestMe_Logout(t *testing.T) {
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodPost || req.URL.Path != "/api/auth/logout" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		return mockJSONResp([]byte(`{"ok":true}`))
+	})
+
+	assertNil(t, mock.Me().Logout(context.Background()))
+}
+
+func TestAdmin_ListAdminUsers(t *testing.T) {
+	expected := types.Users{Data: []types.UserInfo{{ID: "u2"}}, Count: 3000, Page: 2}
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodGet || req.URL.Path != "/api/admin/users" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		q := req.URL.Query()
+		assertEqual(t, q.Get("query"), "adam")
+		assertEqual(t, q.Get("page"), "2")
+		assertEqual(t, q.Get("pageSize"), "100")
+		assertEqual(t, q.Get("orderBy"), "createdAt")
+		assertEqual(t, q.Get("sortDescending"), "true")
+		b, _ := json.Marshal(expected)
+		return mockJSONResp(b)
+	})
+
+	got, err := mock.Admin().ListAdminUsers(context.Background(), types.ListQueryParams{
+		Query:          "adam",
+		Page:           "2",
+		PageSize:       "100",
+		OrderBy:        "createdAt",
+		SortDescending: true,
+	})
+	assertNil(t, err)
+	assertEqual(t, got.Data[0].ID, "u2")
+	assertEqual(t, got.Count, int64(3000))
+}
+
+func TestAdmin_ListAdminWebsites(t *testing.T) {
+	expected := types.Websites{Data: []types.Website{{ID: "w1"}}}
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodGet || req.URL.Path != "/api/admin/websites" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		b, _ := json.Marshal(expected)
+		return mockJSONResp(b)
+	})
+
+	got, err := mock.Admin().ListAdminWebsites(context.Background(), types.ListQueryParams{})
+	assertNil(t, err)
+	assertEqual(t, got.Data[0].ID, "w1")
+}
+
+func TestAdmin_ListAdminTeams(t *testing.T) {
+	expected := types.UserTeams{Data: []types.UserTeam{{ID: "t1"}}}
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodGet || req.URL.Path != "/api/admin/teams" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		b, _ := json.Marshal(expected)
+		return mockJSONResp(b)
+	})
+
+	got, err := mock.Admin().ListAdminTeams(context.Background(), types.ListQueryParams{})
+	assertNil(t, err)
+	assertEqual(t, got.Data[0].ID, "t1")
+}
+
+func TestReport_ListWebsiteReports(t *testing.T) {
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodGet || req.URL.Path != "/api/websites/w1/reports" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		assertEqual(t, req.URL.Query().Get("page"), "2")
+		return mockJSONResp([]byte(`{"data":[{"id":"r1","type":"funnel","parameters":{"window":60}}],"count":1,"page":2}`))
+	})
+
+	got, err := mock.Report().ListWebsiteReports(context.Background(), "w1", types.ListQueryParams{Page: "2"})
+	assertNil(t, err)
+	assertEqual(t, got.Data[0].ID, "r1")
+	assertEqual(t, got.Count, int64(1))
+}
+
+func TestReport_CreateReport(t *testing.T) {
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodPost || req.URL.Path != "/api/reports" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		var body map[string]any
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		assertEqual(t, body["name"], any("Signups"))
+		assertEqual(t, body["parameters"].(map[string]any)["window"], any(float64(60)))
+		return mockJSONResp([]byte(`{"id":"r1","name":"Signups","type":"funnel"}`))
+	})
+
+	got, err := mock.Report().CreateReport(context.Background(), types.SaveReportRequest{
+		WebsiteID:  "w1",
+		Type:       types.ReportTypeFunnel,
+		Name:       "Signups",
+		Parameters: types.ReportFunnelRequest{Window: 60},
+	})
+	assertNil(t, err)
+	assertEqual(t, got.ID, "r1")
+}
+
+func TestReport_UpdateAndDeleteReport(t *testing.T) {
+	var methods []string
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		assertEqual(t, req.URL.Path, "/api/reports/r1")
+		methods = append(methods, req.Method)
+		return mockJSONResp([]byte(`{"id":"r1"}`))
+	})
+
+	_, err := mock.Report().UpdateReport(context.Background(), "r1", types.SaveReportRequest{Name: "Renamed"})
+	assertNil(t, err)
+	assertNil(t, mock.Report().DeleteReport(context.Background(), "r1"))
+	assertEqual(t, strings.Join(methods, ","), "POST,DELETE")
+}
+
+func TestReport_GetReportStringParameters(t *testing.T) {
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		return mockJSONResp([]byte(`{"id":"r1","websiteId":"w1","type":"funnel","parameters":"{\"window\":30,\"steps\":[{\"type\":\"url\",\"value\":\"/\"}]}"}`))
+	})
+
+	report, err := mock.Report().GetReport(context.Background(), "r1")
+	assertNil(t, err)
+
+	params, err := report.Params()
+	assertNil(t, err)
+	funnel, ok := params.(types.ReportFunnelRequest)
+	if !ok {
+		t.Fatalf("expected ReportFunnelRequest, got %T", params)
+	}
+	assertEqual(t, funnel.Window, 30)
+	assertEqual(t, funnel.WebsiteID, "w1")
+	assertEqual(t, funnel.Steps[0].Value, "/")
+}
+
+func TestReport_RunSaved(t *testing.T) {
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		switch req.URL.Path {
+		case "/api/reports/r1":
+			return mockJSONResp([]byte(`{"id":"r1","websiteId":"w1","type":"funnel","parameters":{"window":60}}`))
+		case "/api/reports/funnel":
+			var body types.ReportFunnelRequest
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			assertEqual(t, body.WebsiteID, "w1")
+			assertEqual(t, body.Window, 60)
+			return mockJSONResp([]byte(`[{"value":"signup","visitors":50}]`))
+		}
+		t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		return nil
+	})
+
+	got, err := mock.Report().RunSaved(context.Background(), "r1")
+	assertNil(t, err)
+	funnel, ok := got.([]types.ReportFunnel)
+	if !ok {
+		t.Fatalf("expected []ReportFunnel, got %T", got)
+	}
+	assertEqual(t, funnel[0].Visitors, 50)
+}
+
+func TestReport_RunSavedRevenue(t *testing.T) {
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		switch req.URL.Path {
+		case "/api/reports/r1":
+			return mockJSONResp([]byte(`{"id":"r1","websiteId":"w1","type":"revenue","parameters":{"currency":"USD"}}`))
+		case "/api/reports/revenue":
+			return mockJSONResp([]byte(`{"total":{"sum":42.5,"count":3}}`))
+		}
+		t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		return nil
+	})
+
+	got, err := mock.Report().RunSaved(context.Background(), "r1")
+	assertNil(t, err)
+	revenue, ok := got.(types.ReportRevenue)
+	if !ok {
+		t.Fatalf("expected ReportRevenue, got %T", got)
+	}
+	assertEqual(t, revenue.Total.Sum, 42.5)
+}
+
+func TestReport_RunSavedUnsupportedType(t *testing.T) {
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		return mockJSONResp([]byte(`{"id":"r1","type":"heatmap"}`))
+	})
+
+	_, err := mock.Report().RunSaved(context.Background(), "r1")
+	if err == nil || !strings.Contains(err.Error(), "heatmap") {
+		t.Fatalf("expected unsupported type error, got %v", err)
+	}
+}
+
+func TestRealtime_GetRealtime(t *testing.T) {
+	startAt := time.UnixMilli(1717236000000)
+	mock := newMockClient(func(req *http.Request) *http.Response {
+		if req.Method != http.MethodGet || req.URL.Path != "/api/realtime/w1" {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+		}
+		assertEqual(t, req.URL.Query().Get("startAt"), "1717236000000")
+		return mockJSONResp([]byte(`{
+			"countries": {"US": 3},
+			"urls": {"/pricing": 5},
+			"referrers": {"google.com": 2},
+			"events": [
+				{"__type": "pageview", "sessionId": "s1", "urlPath": "/pricing", "country": "US", "createdAt": "2024-06-01T10:01:00Z"},
+				{"__type": "event", "sessionId": "s1", "eventName": "signup", "createdAt": "2024-06-01T10:02:00Z"}
+			],
+			"series": {"views": [{"x": "2024-06-01 10:01:00", "y": 4}], "visitors": [{"x": "2024-06-01 10:01:00", "y": 2}]},
+			"totals": {"views": 5, "visitors": 3, "events": 1, "countries": 1},
+			"timestamp": 1717236120000
+		}`))
+	})
+
+	got, err := mock.Realtime().GetRealtime(context.Background(), "w1", types.RealtimeQueryParams{StartAt: startAt})
+	assertNil(t, err)
+	assertEqual(t, got.Countries["US"], int64(3))
+	assertEqual(t, got.URLs["/pricing"], int64(5))
+	assertEqual(t, got.Events[1].Type, types.RealtimeTypeEvent)
+	assertEqual(t, got.Events[1].EventName, "signup")
+	assertEqual(t, got.Series.Views[0].NumberOfVisitors, 4)
+	assertEqual(t, got.Totals.Visitors, int64(3))
+	assertEqual(t, got.Timestamp, int64(1717236120000))
+}
+
+func TestPublic_SendBatchChunksAndReportsItems(t *testing.T) {
+	var sizes []int
+	c := NewClient("https://example.com",
+		WithApiKey("test"),
+		WithBatchSize(2),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(req *http.Request) *http.Response {
+			if req.Method != http.MethodPost || req.URL.Path != "/api/batch" {
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			}
+			assertEqual(t, req.Header.Get("User-Agent"), "TestAgent")
+			assertEqual(t, req.Header.Get("x-umami-api-key"), "")
+
+			var events []types.SendEventRequest
+			_ = json.NewDecoder(req.Body).Decode(&events)
+			sizes = append(sizes, len(events))
+			if len(sizes) == 2 {
+				return mockJSONResp([]byte(`{"size":2,"processed":1,"errors":1,"details":[{"index":1,"response":{"error":"invalid"}}]}`))
+			}
+			return mockJSONResp([]byte(fmt.Sprintf(`{"size":%d,"processed":%d}`, len(events), len(events))))
+		}}}),
+	)
+
+	events := slices.Repeat([]types.SendEventRequest{testEvent}, 5)
+	results, err := c.Public().SendBatch(context.Background(), "TestAgent", events)
+	assertNil(t, err)
+	assertEqual(t, fmt.Sprint(sizes), "[2 2 1]")
+	assertEqual(t, len(results), 5)
+	for i, r := range results {
+		assertEqual(t, r.Index, i)
+		if failed := r.Err != nil; failed != (i == 3) {
+			t.Errorf("item %d: unexpected error %v", i, r.Err)
+		}
+	}
+	if !errors.Is(results[3].Err, ErrBatchItemRejected) {
+		t.Errorf("expected ErrBatchItemRejected, got %v", results[3].Err)
+	}
+}
+
+func TestPublic_SendBatchRequestFailure(t *testing.T) {
+	var calls int
+	c := NewClient("https://example.com",
+		WithApiKey("test"),
+		WithBatchSize(1),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(req *http.Request) *http.Response {
+			calls++
+			if calls == 1 {
+				return &http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader("bad")), Header: http.Header{}}
+			}
+			return mockJSONResp([]byte(`{"size":1,"processed":1}`))
+		}}}),
+	)
+
+	results, err := c.Public().SendBatch(context.Background(), "TestAgent", slices.Repeat([]types.SendEventRequest{testEvent}, 2))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 APIError, got %v", err)
+	}
+	assertEqual(t, results[0].Err, err)
+	assertNil(t, results[1].Err)
+}
+
+func TestPublic_SendValidation(t *testing.T) {
+	var sent int
+	c := newMockClient(func(r *http.Request) *http.Response {
+		var events []types.SendEventRequest
+		_ = json.NewDecoder(r.Body).Decode(&events)
+		sent += len(events)
+		return mockJSONResp([]byte(`{"size":1,"processed":1}`))
+	})
+
+	invalid := types.SendEventRequest{Type: "event", Payload: types.SendEventPayload{Website: "w1"}}
+	_, err := c.Public().Send(context.Background(), "TestAgent", invalid)
+	if !errors.Is(err, validate.ErrInvalid) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	results, err := c.Public().SendBatch(context.Background(), "TestAgent", []types.SendEventRequest{invalid, testEvent})
+	assertNil(t, err)
+	assertEqual(t, sent, 1)
+	if !errors.Is(results[0].Err, validate.ErrInvalid) || results[1].Err != nil {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	passThrough := NewClient("https://example.com",
+		WithValidator(validate.Validator{Mode: validate.PassThrough}),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(*http.Request) *http.Response {
+			return mockJSONResp([]byte(`{}`))
+		}}}),
+	)
+	_, err = passThrough.Public().Send(context.Background(), "TestAgent", invalid)
+	assertNil(t, err)
+}
+
+func TestPublic_SendScrubsPayload(t *testing.T) {
+	c := NewClient("https://example.com",
+		WithScrubber(scrub.Default()),
+		WithHttpClient(&http.Client{Transport: &mockRoundTripper{fn: func(r *http.Request) *http.Response {
+			var body types.SendEventRequest
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			assertEqual(t, body.Payload.URL, "/users/:id")
+			assertEqual(t, body.Payload.Title, "Profile of [email]")
+			return mockJSONResp([]byte(`{}`))
+		}}}),
+	)
+
+	event := testEvent
+	event.Payload.URL = "/users/42?token=secret"
+	event.Payload.Title = "Profile of jane@example.com"
+	_, err := c.Public().Send(context.Background(), "TestAgent", event)
+	assertNil(t, err)
+}
@@ -0,0 +1,22 @@
+package umami
+
+import (
+	"errors"
+	"github.com/AdamShannag/umami-client/umami/request"
+)
+
+// APIError is returned by every API method when Umami responds with a non-2xx status.
+// Use errors.As to inspect the status code, endpoint and parsed error body.
+type APIError = request.APIError
+
+// Sentinel errors matched by APIError through errors.Is.
+var (
+	ErrNotFound     = request.ErrNotFound
+	ErrUnauthorized = request.ErrUnauthorized
+	ErrForbidden    = request.ErrForbidden
+	ErrRateLimited  = request.ErrRateLimited
+	ErrServer       = request.ErrServer
+)
+
+// ErrBatchItemRejected is the error of a SendBatch result whose event was rejected by Umami.
+var ErrBatchItemRejected = errors.New("umami: batch event rejected")
@@ -1,20 +1,24 @@
 package umami
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"github.com/AdamShannag/umami-client/umami/api"
+	"github.com/AdamShannag/umami-client/umami/auth/token"
 	"github.com/AdamShannag/umami-client/umami/request"
 	"github.com/AdamShannag/umami-client/umami/types"
 	"net/http"
 	"time"
 )
 
-func (c *client) GetToken(username, password string) (string, time.Duration, error) {
+func (c *client) GetToken(ctx context.Context, username, password string) (string, time.Duration, error) {
 	var resp struct {
 		Token string `json:"token"`
 	}
 
-	if err := c.httpClient.Send(context.Background(), request.Request{
+	if err := c.httpClient.Send(ctx, request.Request{
 		Method:   http.MethodPost,
 		Endpoint: fmt.Sprintf("%s/api/auth/login", c.hostURL),
 		Headers:  nil,
@@ -28,7 +32,7 @@ func (c *client) GetToken(username, password string) (string, time.Duration, err
 		return "", 0, err
 	}
 
-	return resp.Token, c.tokenExpiry, nil
+	return resp.Token, token.Lifetime(resp.Token, c.tokenExpiry), nil
 }
 
 func (c *client) CreateUser(ctx context.Context, req types.CreateUserRequest) (types.User, error) {
@@ -65,6 +69,50 @@ func (c *client) ListUserTeams(ctx context.Context, userId string, params types.
 	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/users/%s/teams", c.hostURL, userId), params.ToQueryMap(), &result)
 }
 
+func (c *client) ListAdminUsers(ctx context.Context, params types.ListQueryParams) (types.Users, error) {
+	var result types.Users
+	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/admin/users", c.hostURL), params.ToQueryMap(), &result)
+}
+
+func (c *client) ListAdminWebsites(ctx context.Context, params types.ListQueryParams) (types.Websites, error) {
+	var result types.Websites
+	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/admin/websites", c.hostURL), params.ToQueryMap(), &result)
+}
+
+func (c *client) ListAdminTeams(ctx context.Context, params types.ListQueryParams) (types.UserTeams, error) {
+	var result types.UserTeams
+	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/admin/teams", c.hostURL), params.ToQueryMap(), &result)
+}
+
+func (c *client) GetMe(ctx context.Context) (types.Me, error) {
+	var result types.Me
+	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/me", c.hostURL), nil, &result)
+}
+
+func (c *client) GetMyWebsites(ctx context.Context, params types.ListQueryParams) (types.UserWebsites, error) {
+	var result types.UserWebsites
+	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/me/websites", c.hostURL), params.ToQueryMap(), &result)
+}
+
+func (c *client) GetMyTeams(ctx context.Context, params types.ListQueryParams) (types.UserTeams, error) {
+	var result types.UserTeams
+	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/me/teams", c.hostURL), params.ToQueryMap(), &result)
+}
+
+func (c *client) UpdateMyPassword(ctx context.Context, req types.UpdatePasswordRequest) (types.User, error) {
+	var result types.User
+	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/me/password", c.hostURL), req, &result)
+}
+
+func (c *client) VerifyToken(ctx context.Context) (types.CurrentUser, error) {
+	var result types.CurrentUser
+	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/auth/verify", c.hostURL), nil, &result)
+}
+
+func (c *client) Logout(ctx context.Context) error {
+	return c.postRequest(ctx, fmt.Sprintf("%s/api/auth/logout", c.hostURL), nil, nil)
+}
+
 func (c *client) CreateTeam(ctx context.Context, req types.CreateTeamRequest) ([]types.Team, error) {
 	var result []types.Team
 	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/teams", c.hostURL), req, &result)
@@ -210,6 +258,11 @@ func (c *client) GetWebsiteActiveUsers(ctx context.Context, websiteId string) (t
 	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/websites/%s/active", c.hostURL, websiteId), nil, &result)
 }
 
+func (c *client) GetRealtime(ctx context.Context, websiteId string, params types.RealtimeQueryParams) (types.Realtime, error) {
+	var result types.Realtime
+	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/realtime/%s", c.hostURL, websiteId), params.ToQueryMap(), &result)
+}
+
 func (c *client) GetWebsiteEvents(ctx context.Context, websiteId string, params types.WebsiteEventsQueryParams) (types.WebsiteEvents, error) {
 	var result types.WebsiteEvents
 	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/websites/%s/events", c.hostURL, websiteId), params.ToQueryMap(), &result)
@@ -230,55 +283,222 @@ func (c *client) GetWebsiteStats(ctx context.Context, websiteId string, params t
 	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/websites/%s/stats", c.hostURL, websiteId), params.ToQueryMap(), &result)
 }
 
-func (c *client) Send(ctx context.Context, userAgent string, payload types.SendEventRequest) error {
-	return c.httpClient.Send(ctx, request.Request{
+func (c *client) Send(ctx context.Context, userAgent string, payload types.SendEventRequest, opts ...api.SendOption) (types.SendResponse, error) {
+	o := api.NewSendOptions(opts...)
+	if payload.Payload.IP == "" {
+		payload.Payload.IP = o.ClientIP
+	}
+	if c.scrubber != nil {
+		c.scrubber.Payload(&payload.Payload)
+	}
+	if err := c.validator.Validate(&payload.Payload); err != nil {
+		return types.SendResponse{}, err
+	}
+
+	var body []byte
+	err := c.httpClient.Send(ctx, request.Request{
 		Method:   http.MethodPost,
 		Endpoint: fmt.Sprintf("%s/api/send", c.hostURL),
-		Headers: map[string]string{
-			"User-Agent": userAgent,
-		},
-		Query:   nil,
-		Payload: payload,
-		Public:  true,
-	}, nil)
+		Headers:  sendHeaders(userAgent, o),
+		Query:    nil,
+		Payload:  payload,
+		Public:   true,
+	}, &body)
+	if err != nil {
+		return types.SendResponse{}, err
+	}
+	return parseSendResponse(body), nil
+}
+
+// parseSendResponse reads the body of a successful /api/send. Umami 2.x answers with the plain
+// cache token and some versions with an empty body; neither means the event was lost.
+func parseSendResponse(body []byte) types.SendResponse {
+	body = bytes.TrimSpace(body)
+	var result types.SendResponse
+	if len(body) == 0 || json.Unmarshal(body, &result) == nil {
+		return result
+	}
+	return types.SendResponse{Cache: string(body)}
+}
+
+// sendHeaders returns the headers of a tracking request. Explicit headers take precedence.
+func sendHeaders(userAgent string, o api.SendOptions) map[string]string {
+	headers := map[string]string{
+		"User-Agent": userAgent,
+	}
+	if o.ClientIP != "" {
+		headers["X-Forwarded-For"] = o.ClientIP
+		headers["X-Real-IP"] = o.ClientIP
+	}
+	if o.AcceptLanguage != "" {
+		headers["Accept-Language"] = o.AcceptLanguage
+	}
+	for k, v := range o.Headers {
+		headers[k] = v
+	}
+	return headers
+}
+
+func (c *client) SendBatch(ctx context.Context, userAgent string, events []types.SendEventRequest, opts ...api.SendOption) ([]types.BatchItemResult, error) {
+	o := api.NewSendOptions(opts...)
+	results := make([]types.BatchItemResult, len(events))
+
+	// Invalid events get their validation error and are left out of the requests;
+	// index maps the position of each sent event back to events.
+	pending := make([]types.SendEventRequest, 0, len(events))
+	index := make([]int, 0, len(events))
+	for i, event := range events {
+		results[i].Index = i
+		if event.Payload.IP == "" {
+			event.Payload.IP = o.ClientIP
+		}
+		if c.scrubber != nil {
+			c.scrubber.Payload(&event.Payload)
+		}
+		if err := c.validator.Validate(&event.Payload); err != nil {
+			results[i].Err = err
+			continue
+		}
+		pending = append(pending, event)
+		index = append(index, i)
+	}
+
+	var firstErr error
+	for start := 0; start < len(pending); start += c.batchSize {
+		end := min(start+c.batchSize, len(pending))
+
+		var resp types.SendBatchResponse
+		err := c.httpClient.Send(ctx, request.Request{
+			Method:   http.MethodPost,
+			Endpoint: fmt.Sprintf("%s/api/batch", c.hostURL),
+			Headers:  sendHeaders(userAgent, o),
+			Payload:  pending[start:end],
+			Public:   true,
+		}, &resp)
+		if err != nil {
+			for _, i := range index[start:end] {
+				results[i].Err = err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				for _, i := range index[end:] {
+					results[i].Err = ctx.Err()
+				}
+				return results, firstErr
+			}
+			continue
+		}
+
+		for _, d := range resp.Details {
+			if d.Index >= 0 && start+d.Index < end {
+				results[index[start+d.Index]].Err = fmt.Errorf("%w: %s", ErrBatchItemRejected, d.Response)
+			}
+		}
+	}
+
+	return results, firstErr
 }
 
 func (c *client) GetInsights(ctx context.Context, payload types.ReportInsightsRequest) ([]types.ReportInsight, error) {
 	var result []types.ReportInsight
-	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/reports/insights", c.hostURL), payload, &result)
+	return result, c.queryRequest(ctx, fmt.Sprintf("%s/api/reports/insights", c.hostURL), payload, &result)
 }
 
 func (c *client) GetFunnel(ctx context.Context, payload types.ReportFunnelRequest) ([]types.ReportFunnel, error) {
 	var result []types.ReportFunnel
-	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/reports/funnel", c.hostURL), payload, &result)
+	return result, c.queryRequest(ctx, fmt.Sprintf("%s/api/reports/funnel", c.hostURL), payload, &result)
 }
 
 func (c *client) GetRetention(ctx context.Context, payload types.ReportRetentionRequest) ([]types.ReportRetention, error) {
 	var result []types.ReportRetention
-	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/reports/retention", c.hostURL), payload, &result)
+	return result, c.queryRequest(ctx, fmt.Sprintf("%s/api/reports/retention", c.hostURL), payload, &result)
 }
 
 func (c *client) GetUTM(ctx context.Context, payload types.ReportUTMRequest) (types.ReportUTM, error) {
 	var result types.ReportUTM
-	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/reports/utm", c.hostURL), payload, &result)
+	return result, c.queryRequest(ctx, fmt.Sprintf("%s/api/reports/utm", c.hostURL), payload, &result)
 }
 
 func (c *client) GetGoals(ctx context.Context, payload types.ReportGoalsRequest) ([]types.ReportGoal, error) {
 	var result []types.ReportGoal
-	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/reports/goals", c.hostURL), payload, &result)
+	return result, c.queryRequest(ctx, fmt.Sprintf("%s/api/reports/goals", c.hostURL), payload, &result)
 }
 
 func (c *client) GetJourney(ctx context.Context, payload types.ReportJourneyRequest) ([]types.ReportJourney, error) {
 	var result []types.ReportJourney
-	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/reports/journey", c.hostURL), payload, &result)
+	return result, c.queryRequest(ctx, fmt.Sprintf("%s/api/reports/journey", c.hostURL), payload, &result)
 }
 
 func (c *client) GetRevenue(ctx context.Context, payload types.ReportRevenueRequest) (types.ReportRevenue, error) {
 	var result types.ReportRevenue
-	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/reports/attribution", c.hostURL), payload, &result)
+	return result, c.queryRequest(ctx, fmt.Sprintf("%s/api/reports/revenue", c.hostURL), payload, &result)
 }
 
 func (c *client) GetAttribution(ctx context.Context, payload types.ReportAttributionRequest) (types.ReportAttribution, error) {
 	var result types.ReportAttribution
-	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/reports/attribution", c.hostURL), payload, &result)
+	return result, c.queryRequest(ctx, fmt.Sprintf("%s/api/reports/attribution", c.hostURL), payload, &result)
+}
+
+func (c *client) ListReports(ctx context.Context, params types.ListQueryParams) (types.SavedReports, error) {
+	var result types.SavedReports
+	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/reports", c.hostURL), params.ToQueryMap(), &result)
+}
+
+func (c *client) ListWebsiteReports(ctx context.Context, websiteId string, params types.ListQueryParams) (types.SavedReports, error) {
+	var result types.SavedReports
+	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/websites/%s/reports", c.hostURL, websiteId), params.ToQueryMap(), &result)
+}
+
+func (c *client) CreateReport(ctx context.Context, req types.SaveReportRequest) (types.SavedReport, error) {
+	var result types.SavedReport
+	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/reports", c.hostURL), req, &result)
+}
+
+func (c *client) GetReport(ctx context.Context, reportId string) (types.SavedReport, error) {
+	var result types.SavedReport
+	return result, c.getRequest(ctx, fmt.Sprintf("%s/api/reports/%s", c.hostURL, reportId), nil, &result)
+}
+
+func (c *client) UpdateReport(ctx context.Context, reportId string, req types.SaveReportRequest) (types.SavedReport, error) {
+	var result types.SavedReport
+	return result, c.postRequest(ctx, fmt.Sprintf("%s/api/reports/%s", c.hostURL, reportId), req, &result)
+}
+
+func (c *client) DeleteReport(ctx context.Context, reportId string) error {
+	return c.deleteRequest(ctx, fmt.Sprintf("%s/api/reports/%s", c.hostURL, reportId))
+}
+
+func (c *client) RunSaved(ctx context.Context, reportId string) (any, error) {
+	report, err := c.GetReport(ctx, reportId)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := report.Params()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := params.(type) {
+	case types.ReportInsightsRequest:
+		return c.GetInsights(ctx, p)
+	case types.ReportFunnelRequest:
+		return c.GetFunnel(ctx, p)
+	case types.ReportRetentionRequest:
+		return c.GetRetention(ctx, p)
+	case types.ReportUTMRequest:
+		return c.GetUTM(ctx, p)
+	case types.ReportGoalsRequest:
+		return c.GetGoals(ctx, p)
+	case types.ReportJourneyRequest:
+		return c.GetJourney(ctx, p)
+	case types.ReportRevenueRequest:
+		return c.GetRevenue(ctx, p)
+	case types.ReportAttributionRequest:
+		return c.GetAttribution(ctx, p)
+	default:
+		return nil, fmt.Errorf("unsupported report type %q", report.Type)
+	}
 }
@@ -0,0 +1,124 @@
+// Package publictest provides a recording api.Public for the tests of the tracking helpers.
+package publictest
+
+import (
+	"context"
+	"github.com/AdamShannag/umami-client/umami/api"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Call is an event recorded by Public.
+type Call struct {
+	UserAgent string
+	Event     types.SendEventRequest
+	Options   api.SendOptions
+	Batch     bool // Sent through SendBatch
+}
+
+// Public is an api.Public recording the events it receives. The zero value records every event.
+type Public struct {
+	// Gate, when non-nil, blocks sends until it is closed or their context is done.
+	Gate chan struct{}
+	// Err returns the error of an event; the events it returns nil for are recorded.
+	Err func(event types.SendEventRequest) error
+	// BatchErr is returned by SendBatch along with the result of each event.
+	BatchErr error
+	// Notify, when non-nil, receives every recorded call.
+	Notify chan Call
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+// New returns a Public notifying its calls, for use with Next and None.
+func New() *Public {
+	return &Public{Notify: make(chan Call, 16)}
+}
+
+func (p *Public) Send(ctx context.Context, userAgent string, event types.SendEventRequest, opts ...api.SendOption) (types.SendResponse, error) {
+	if err := p.wait(ctx); err != nil {
+		return types.SendResponse{}, err
+	}
+	return types.SendResponse{}, p.record(Call{UserAgent: userAgent, Event: event, Options: api.NewSendOptions(opts...)})
+}
+
+func (p *Public) SendBatch(ctx context.Context, userAgent string, events []types.SendEventRequest, opts ...api.SendOption) ([]types.BatchItemResult, error) {
+	if err := p.wait(ctx); err != nil {
+		return nil, err
+	}
+	o := api.NewSendOptions(opts...)
+	results := make([]types.BatchItemResult, len(events))
+	for i, event := range events {
+		results[i] = types.BatchItemResult{Index: i, Err: p.record(Call{UserAgent: userAgent, Event: event, Options: o, Batch: true})}
+	}
+	return results, p.BatchErr
+}
+
+func (p *Public) wait(ctx context.Context) error {
+	if p.Gate == nil {
+		return nil
+	}
+	select {
+	case <-p.Gate:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Public) record(c Call) error {
+	if p.Err != nil {
+		if err := p.Err(c.Event); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	p.calls = append(p.calls, c)
+	p.mu.Unlock()
+	if p.Notify != nil {
+		p.Notify <- c
+	}
+	return nil
+}
+
+// Calls returns the recorded calls in order.
+func (p *Public) Calls() []Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Call(nil), p.calls...)
+}
+
+// URLs returns the payload URLs of the recorded calls in order.
+func (p *Public) URLs() []string {
+	calls := p.Calls()
+	urls := make([]string, len(calls))
+	for i, c := range calls {
+		urls[i] = c.Event.Payload.URL
+	}
+	return urls
+}
+
+// Next waits for the next notified call.
+func (p *Public) Next(t testing.TB) Call {
+	t.Helper()
+	select {
+	case c := <-p.Notify:
+		return c
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event sent")
+		return Call{}
+	}
+}
+
+// None checks that no call is notified for a short while.
+func (p *Public) None(t testing.TB) {
+	t.Helper()
+	select {
+	case c := <-p.Notify:
+		t.Fatalf("unexpected event for %s", c.Event.Payload.URL)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
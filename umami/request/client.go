@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"github.com/AdamShannag/umami-client/umami/auth"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 type Request struct {
@@ -18,23 +20,40 @@ type Request struct {
 	Query    map[string]string
 	Payload  any
 	Public   bool
+	// Idempotent marks a non-GET request as safe to retry, e.g. report queries sent as POST.
+	Idempotent bool
+	// Group overrides the endpoint group used for rate limiting; it is derived from Endpoint when empty.
+	Group Group
 }
 
 type Client interface {
 	Send(ctx context.Context, req Request, res any) error
 	WithHttpClient(*http.Client)
 	WithAuth(auth.Auth)
+	WithRetry(RetryPolicy)
+	WithRateLimit(rps float64, burst int, groups ...Group)
+	WithMaxInFlight(n int, groups ...Group)
+	WithMiddleware(...Interceptor)
+	WithLogger(*slog.Logger, LogLevels)
 }
 
 type client struct {
 	auth       auth.Auth
 	httpClient *http.Client
+	retry      RetryPolicy
+	limiter    *limiter
+
+	interceptors []Interceptor
+
+	logger    *slog.Logger
+	logLevels LogLevels
 }
 
 func NewClient() Client {
 	return &client{
 		auth:       auth.NewDefaultAuth(),
 		httpClient: http.DefaultClient,
+		limiter:    newLimiter(),
 	}
 }
 
@@ -43,37 +62,113 @@ func (c *client) Send(ctx context.Context, req Request, res any) error {
 		req.Headers = map[string]string{}
 	}
 
-	reqURL := c.withQuery(req.Endpoint, req.Query)
-	body, err := c.marshalBody(req.Payload)
-	if err != nil {
-		return err
+	return chain(c.send, c.interceptors)(ctx, req, res)
+}
+
+// send marshals the payload once and performs the attempts allowed by the retry policy.
+func (c *client) send(ctx context.Context, req Request, res any) error {
+	if req.Headers == nil {
+		req.Headers = map[string]string{}
 	}
 
-	r, err := http.NewRequestWithContext(ctx, req.Method, reqURL, body)
+	body, err := c.marshalBody(req.Payload)
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return err
 	}
 
 	if req.Payload != nil {
 		req.Headers["Content-Type"] = "application/json"
 	}
+
+	renewed := false
+	for attempt := 1; ; attempt++ {
+		key, err := c.credential(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		start := time.Now()
+		status, err := c.do(ctx, req, key, body, res)
+
+		// A rejected token is renewed once and the request replayed; this does not count as a retry.
+		if status == http.StatusUnauthorized && !renewed && c.renew(ctx, req, key) {
+			renewed = true
+			c.logAttempt(ctx, req, body, attempt, status, time.Since(start), true, err)
+			attempt--
+			continue
+		}
+
+		retry := err != nil && c.retry.retry(req, attempt, err)
+		c.logAttempt(ctx, req, body, attempt, status, time.Since(start), retry, err)
+		if !retry {
+			return err
+		}
+
+		if sleepErr := sleep(ctx, c.retry.delay(attempt, err)); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// do performs a single attempt, rebuilding the request body from the marshaled payload.
+// It returns the response status, or 0 when no response was received.
+func (c *client) do(ctx context.Context, req Request, key string, body []byte, res any) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	r, err := http.NewRequestWithContext(ctx, req.Method, c.withQuery(req.Endpoint, req.Query), reader)
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
 	c.setHeaders(r, req.Headers)
 
 	if !req.Public {
-		key, authErr := c.auth.Get()
-		if authErr != nil {
-			return fmt.Errorf("auth: %w", authErr)
-		}
 		r.Header.Set(c.auth.Header(), key)
 	}
 
+	group := req.Group
+	if group == "" {
+		group = GroupOf(req.Endpoint)
+	}
+	release, err := c.limiter.acquire(ctx, group)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
 	resp, err := c.httpClient.Do(r)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer resp.Body.Close()
 
-	return c.decodeResponse(resp, res)
+	return resp.StatusCode, c.decodeResponse(req, resp, res)
+}
+
+// credential returns the auth value for the request, or an empty string for public requests.
+func (c *client) credential(ctx context.Context, req Request) (string, error) {
+	if req.Public {
+		return "", nil
+	}
+	key, err := c.auth.Get(ctx)
+	if err != nil {
+		return "", fmt.Errorf("auth: %w", err)
+	}
+	return key, nil
+}
+
+// renew asks the auth implementation to replace the rejected credential, reporting whether it did.
+func (c *client) renew(ctx context.Context, req Request, stale string) bool {
+	if req.Public {
+		return false
+	}
+	renewer, ok := c.auth.(auth.Renewer)
+	if !ok {
+		return false
+	}
+	return renewer.Renew(ctx, stale) == nil
 }
 
 func (c *client) WithHttpClient(h *http.Client) {
@@ -84,6 +179,33 @@ func (c *client) WithAuth(a auth.Auth) {
 	c.auth = a
 }
 
+func (c *client) WithRetry(p RetryPolicy) {
+	c.retry = p
+}
+
+// WithRateLimit limits the given groups, or every group when none is given, to rps requests
+// per second with bursts of up to burst requests. Each group gets its own bucket.
+func (c *client) WithRateLimit(rps float64, burst int, groups ...Group) {
+	c.limiter.setRate(rps, burst, groups)
+}
+
+// WithMaxInFlight caps the number of concurrent requests of the given groups, or of every group
+// when none is given. Each group gets its own cap.
+func (c *client) WithMaxInFlight(n int, groups ...Group) {
+	c.limiter.setMaxInFlight(n, groups)
+}
+
+// WithMiddleware appends interceptors to the chain wrapping every request, the first one being the outermost.
+func (c *client) WithMiddleware(interceptors ...Interceptor) {
+	c.interceptors = append(c.interceptors, interceptors...)
+}
+
+// WithLogger logs every request attempt to l at the given levels. A nil logger disables logging.
+func (c *client) WithLogger(l *slog.Logger, levels LogLevels) {
+	c.logger = l
+	c.logLevels = levels
+}
+
 func (c *client) setHeaders(req *http.Request, headers map[string]string) {
 	req.Header.Set("Accept", "application/json")
 
@@ -92,7 +214,7 @@ func (c *client) setHeaders(req *http.Request, headers map[string]string) {
 	}
 }
 
-func (c *client) marshalBody(payload any) (io.Reader, error) {
+func (c *client) marshalBody(payload any) ([]byte, error) {
 	if payload == nil {
 		return nil, nil
 	}
@@ -100,18 +222,24 @@ func (c *client) marshalBody(payload any) (io.Reader, error) {
 	if err != nil {
 		return nil, fmt.Errorf("marshal payload: %w", err)
 	}
-	return bytes.NewReader(bodyBytes), nil
+	return bodyBytes, nil
 }
 
-func (c *client) decodeResponse(resp *http.Response, v any) error {
+func (c *client) decodeResponse(req Request, resp *http.Response, v any) error {
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("request failed [%d]: %s", resp.StatusCode, string(bodyBytes))
+		return newAPIError(req, resp, bodyBytes)
 	}
 
 	if v == nil {
 		return nil
 	}
+	// A *[]byte receives the raw body, for responses that are not always JSON.
+	if raw, ok := v.(*[]byte); ok {
+		var err error
+		*raw, err = io.ReadAll(resp.Body)
+		return err
+	}
 
 	return json.NewDecoder(resp.Body).Decode(v)
 }
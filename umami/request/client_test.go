@@ -1,13 +1,19 @@
 package request_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"github.com/AdamShannag/umami-client/umami/request"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 type mockAuth struct {
@@ -16,8 +22,8 @@ type mockAuth struct {
 	err    error
 }
 
-func (m mockAuth) Header() string       { return m.header }
-func (m mockAuth) Get() (string, error) { return m.token, m.err }
+func (m mockAuth) Header() string                      { return m.header }
+func (m mockAuth) Get(context.Context) (string, error) { return m.token, m.err }
 
 type mockResponse struct {
 	Message string `json:"message"`
@@ -62,6 +68,30 @@ func TestSend_Success(t *testing.T) {
 	}
 }
 
+func TestSend_RawBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+
+	var body []byte
+	err := client.Send(context.Background(), request.Request{
+		Method:   http.MethodPost,
+		Endpoint: server.URL,
+		Public:   true,
+	}, &body)
+
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if string(body) != "not json" {
+		t.Errorf("unexpected body: %q", body)
+	}
+}
+
 func TestSend_WithPayload(t *testing.T) {
 	expected := mockResponse{Message: "created"}
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -129,3 +159,451 @@ func TestSend_FailureResponse(t *testing.T) {
 		t.Errorf("expected 400 error, got %v", err)
 	}
 }
+
+func TestSend_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "req-42")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":{"code":"not-found","message":"Website not found"}}`))
+	}))
+	defer server.Close()
+
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(mockAuth{header: "Authorization", token: "abc"})
+
+	err := client.Send(context.Background(), request.Request{
+		Method:   http.MethodGet,
+		Endpoint: server.URL + "/api/websites/w1",
+	}, nil)
+
+	if !errors.Is(err, request.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if errors.Is(err, request.ErrUnauthorized) {
+		t.Errorf("did not expect ErrUnauthorized")
+	}
+
+	var apiErr *request.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Method != http.MethodGet {
+		t.Errorf("unexpected status/method: %d %s", apiErr.StatusCode, apiErr.Method)
+	}
+	if apiErr.Code != "not-found" || apiErr.Message != "Website not found" {
+		t.Errorf("unexpected parsed body: %q %q", apiErr.Code, apiErr.Message)
+	}
+	if apiErr.RequestID != "req-42" {
+		t.Errorf("expected request id req-42, got %q", apiErr.RequestID)
+	}
+}
+
+func TestSend_APIErrorServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Internal Server Error", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(mockAuth{header: "Authorization", token: "abc"})
+
+	err := client.Send(context.Background(), request.Request{
+		Method:   http.MethodGet,
+		Endpoint: server.URL,
+	}, nil)
+
+	if !errors.Is(err, request.ErrServer) {
+		t.Fatalf("expected ErrServer, got %v", err)
+	}
+	var apiErr *request.APIError
+	if errors.As(err, &apiErr) && apiErr.Message != "Internal Server Error" {
+		t.Errorf("unexpected message: %q", apiErr.Message)
+	}
+}
+
+func TestSend_RetryOnServiceUnavailable(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body mockPayload
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Data != "test" {
+			t.Errorf("expected body to be resent, got %+v", body)
+		}
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(mockResponse{Message: "ok"})
+	}))
+	defer server.Close()
+
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(mockAuth{header: "Authorization", token: "abc"})
+	client.WithRetry(request.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	var res mockResponse
+	err := client.Send(context.Background(), request.Request{
+		Method:     http.MethodPost,
+		Endpoint:   server.URL,
+		Payload:    mockPayload{Data: "test"},
+		Idempotent: true,
+	}, &res)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 3 || res.Message != "ok" {
+		t.Errorf("expected 3 calls and ok response, got %d %+v", calls.Load(), res)
+	}
+}
+
+func TestSend_NoRetryForUnsafePost(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(mockAuth{header: "Authorization", token: "abc"})
+	client.WithRetry(request.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	err := client.Send(context.Background(), request.Request{
+		Method:   http.MethodPost,
+		Endpoint: server.URL,
+		Payload:  mockPayload{Data: "test"},
+	}, nil)
+
+	if !errors.Is(err, request.ErrServer) {
+		t.Fatalf("expected ErrServer, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected a single attempt, got %d", calls.Load())
+	}
+}
+
+func TestSend_NoRetryBeyondMaxRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(mockAuth{header: "Authorization", token: "abc"})
+	client.WithRetry(request.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxRetryAfter: time.Minute})
+
+	start := time.Now()
+	err := client.Send(context.Background(), request.Request{
+		Method:   http.MethodGet,
+		Endpoint: server.URL,
+	}, nil)
+
+	var apiErr *request.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected a 429 APIError, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected a single attempt, got %d", calls.Load())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected no wait, took %v", elapsed)
+	}
+}
+
+func TestSend_RetryHook(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var attempts []int
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(mockAuth{header: "Authorization", token: "abc"})
+	client.WithRetry(request.RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Millisecond,
+		ShouldRetry: func(req request.Request, attempt int, err error) bool {
+			attempts = append(attempts, attempt)
+			return errors.Is(err, request.ErrServer) && attempt < 2
+		},
+	})
+
+	err := client.Send(context.Background(), request.Request{
+		Method:   http.MethodGet,
+		Endpoint: server.URL,
+	}, nil)
+
+	if !errors.Is(err, request.ErrServer) {
+		t.Fatalf("expected ErrServer, got %v", err)
+	}
+	if calls.Load() != 2 || len(attempts) != 2 {
+		t.Errorf("expected 2 calls and 2 hook invocations, got %d %v", calls.Load(), attempts)
+	}
+}
+
+func TestSend_MaxInFlight(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(mockAuth{header: "Authorization", token: "abc"})
+	client.WithMaxInFlight(2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := client.Send(context.Background(), request.Request{Method: http.MethodGet, Endpoint: server.URL}, nil); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak.Load() > 2 {
+		t.Errorf("expected at most 2 concurrent requests, got %d", peak.Load())
+	}
+}
+
+func TestSend_RateLimitRespectsContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(mockAuth{header: "Authorization", token: "abc"})
+	client.WithRateLimit(0.1, 1, request.GroupReports)
+
+	send := func(ctx context.Context, endpoint string) error {
+		return client.Send(ctx, request.Request{Method: http.MethodGet, Endpoint: endpoint}, nil)
+	}
+
+	if err := send(context.Background(), server.URL+"/api/reports/funnel"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := send(ctx, server.URL+"/api/reports/funnel"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded while waiting for a token, got %v", err)
+	}
+
+	if err := send(context.Background(), server.URL+"/api/send"); err != nil {
+		t.Fatalf("tracking group should not be limited: %v", err)
+	}
+}
+
+func TestGroupOf(t *testing.T) {
+	cases := map[string]request.Group{
+		"https://umami.example.com/api/send":              request.GroupTracking,
+		"https://umami.example.com/api/batch":             request.GroupTracking,
+		"https://umami.example.com/api/reports/funnel":    request.GroupReports,
+		"https://umami.example.com/api/websites/w1/stats": request.GroupDefault,
+		"https://example.com/umami/api/send":              request.GroupTracking,
+		"https://example.com/umami/api/batch":             request.GroupTracking,
+		"https://example.com/umami/api/reports/revenue":   request.GroupReports,
+		"https://umami.example.com/api/reports":           request.GroupDefault,
+		"https://umami.example.com/api/reports/r1":        request.GroupDefault,
+		"https://umami.example.com/api/websites/w1/send":  request.GroupDefault,
+	}
+	for endpoint, want := range cases {
+		if got := request.GroupOf(endpoint); got != want {
+			t.Errorf("GroupOf(%s) = %s, want %s", endpoint, got, want)
+		}
+	}
+}
+
+func TestSend_MiddlewareOrderAndHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Tenant") != "acme" {
+			t.Errorf("expected tenant header, got %q", r.Header.Get("X-Tenant"))
+		}
+		json.NewEncoder(w).Encode(mockResponse{Message: "hello"})
+	}))
+	defer server.Close()
+
+	var order []string
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(mockAuth{header: "Authorization", token: "abc"})
+	client.WithMiddleware(
+		request.Before(func(ctx context.Context, req *request.Request) error {
+			order = append(order, "before")
+			req.Headers["X-Tenant"] = "acme"
+			return nil
+		}),
+		request.After(func(ctx context.Context, req request.Request, res any, err error) error {
+			order = append(order, "after:"+res.(*mockResponse).Message)
+			return err
+		}),
+	)
+
+	var res mockResponse
+	if err := client.Send(context.Background(), request.Request{Method: http.MethodGet, Endpoint: server.URL}, &res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(order) != 2 || order[0] != "before" || order[1] != "after:hello" {
+		t.Errorf("unexpected interceptor order: %v", order)
+	}
+}
+
+func TestSend_MiddlewareShortCircuit(t *testing.T) {
+	client := request.NewClient()
+	client.WithHttpClient(&http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		t.Fatalf("request should not reach the transport")
+		return nil, nil
+	})})
+	client.WithMiddleware(request.InterceptorFunc(func(ctx context.Context, req request.Request, res any, next request.Handler) error {
+		res.(*mockResponse).Message = "cached"
+		return nil
+	}))
+
+	var res mockResponse
+	if err := client.Send(context.Background(), request.Request{Method: http.MethodGet, Endpoint: "http://fake"}, &res); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Message != "cached" {
+		t.Errorf("expected cached response, got %+v", res)
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestSend_LoggerRedactsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"token":"abc"}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(mockAuth{header: "x-umami-api-key", token: "secret-key"})
+	client.WithLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})), request.DefaultLogLevels())
+
+	err := client.Send(context.Background(), request.Request{
+		Method:   http.MethodPost,
+		Endpoint: server.URL + "/api/auth/login",
+		Headers:  map[string]string{"Authorization": "Bearer secret-token"},
+		Query:    map[string]string{"foo": "bar"},
+		Payload:  map[string]string{"username": "admin", "password": "secret-password"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	for _, secret := range []string{"secret-key", "secret-token", "secret-password"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log output leaks %q: %s", secret, out)
+		}
+	}
+	for _, want := range []string{`"method":"POST"`, `"status":200`, `"attempt":1`, `"foo":"bar"`, "admin"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output missing %s: %s", want, out)
+		}
+	}
+}
+
+type renewingAuth struct {
+	mu     sync.Mutex
+	token  string
+	renews int
+}
+
+func (a *renewingAuth) Header() string { return "Authorization" }
+func (a *renewingAuth) Get(context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.token, nil
+}
+func (a *renewingAuth) Renew(ctx context.Context, stale string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.renews++
+	a.token = "fresh"
+	return nil
+}
+
+func TestSend_RenewOnUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body mockPayload
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Data != "test" {
+			t.Errorf("expected body to be replayed, got %+v", body)
+		}
+		if r.Header.Get("Authorization") != "fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(mockResponse{Message: "ok"})
+	}))
+	defer server.Close()
+
+	a := &renewingAuth{token: "expired"}
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(a)
+
+	var res mockResponse
+	err := client.Send(context.Background(), request.Request{
+		Method:   http.MethodPost,
+		Endpoint: server.URL,
+		Payload:  mockPayload{Data: "test"},
+	}, &res)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.renews != 1 || res.Message != "ok" {
+		t.Errorf("expected one renewal and ok response, got %d %+v", a.renews, res)
+	}
+}
+
+func TestSend_RenewOnlyOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	a := &renewingAuth{token: "expired"}
+	client := request.NewClient()
+	client.WithHttpClient(server.Client())
+	client.WithAuth(a)
+
+	err := client.Send(context.Background(), request.Request{Method: http.MethodGet, Endpoint: server.URL}, nil)
+	if !errors.Is(err, request.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	if a.renews != 1 {
+		t.Errorf("expected a single renewal, got %d", a.renews)
+	}
+}
@@ -0,0 +1,118 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound matches API errors with status 404.
+	ErrNotFound = errors.New("umami: not found")
+	// ErrUnauthorized matches API errors with status 401.
+	ErrUnauthorized = errors.New("umami: unauthorized")
+	// ErrForbidden matches API errors with status 403.
+	ErrForbidden = errors.New("umami: forbidden")
+	// ErrRateLimited matches API errors with status 429.
+	ErrRateLimited = errors.New("umami: rate limited")
+	// ErrServer matches API errors with a 5xx status.
+	ErrServer = errors.New("umami: server error")
+)
+
+// requestIDHeaders are checked in order to find an identifier for the failed request.
+var requestIDHeaders = []string{"X-Request-Id", "X-Correlation-Id", "X-Amzn-Trace-Id", "Cf-Ray"}
+
+// APIError is returned when the Umami server responds with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Endpoint   string
+	// Message is the error message parsed from the response body, if any.
+	Message string
+	// Code is the error code parsed from the response body, if any.
+	Code string
+	// Body is the raw response body.
+	Body      string
+	RequestID string
+	// RetryAfter is the delay requested by the server through the Retry-After header.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("request failed [%d]: %s", e.StatusCode, e.Body)
+}
+
+// Is reports whether the error matches one of the package sentinels.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500 && e.StatusCode <= 599
+	}
+	return false
+}
+
+func newAPIError(req Request, resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Method:     req.Method,
+		Endpoint:   req.Endpoint,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	apiErr.Code, apiErr.Message = parseErrorBody(body)
+
+	for _, h := range requestIDHeaders {
+		if id := resp.Header.Get(h); id != "" {
+			apiErr.RequestID = id
+			break
+		}
+	}
+
+	return apiErr
+}
+
+// parseErrorBody extracts the code and message from the error bodies Umami returns,
+// which are either plain text, {"error": "..."}, {"message": "..."} or
+// {"error": {"code": "...", "message": "..."}}.
+func parseErrorBody(body []byte) (string, string) {
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+		Code    string          `json:"code"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", strings.TrimSpace(string(body))
+	}
+
+	code, message := parsed.Code, parsed.Message
+	if len(parsed.Error) > 0 {
+		var nested struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		var text string
+		if json.Unmarshal(parsed.Error, &nested) == nil {
+			if nested.Code != "" {
+				code = nested.Code
+			}
+			if nested.Message != "" {
+				message = nested.Message
+			}
+		} else if json.Unmarshal(parsed.Error, &text) == nil && message == "" {
+			message = text
+		}
+	}
+
+	return code, message
+}
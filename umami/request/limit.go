@@ -0,0 +1,163 @@
+package request
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Group identifies a class of endpoints whose rate and concurrency are limited independently,
+// so that heavy report queries cannot starve tracking traffic.
+type Group string
+
+const (
+	// GroupDefault covers every endpoint not matched by another group.
+	GroupDefault Group = "default"
+	// GroupTracking covers the public tracking endpoints (/api/send, /api/batch).
+	GroupTracking Group = "tracking"
+	// GroupReports covers the report queries (/api/reports/funnel, /api/reports/insights, ...).
+	// Saved report management falls under GroupDefault.
+	GroupReports Group = "reports"
+)
+
+var allGroups = []Group{GroupDefault, GroupTracking, GroupReports}
+
+// reportQueries are the report types run through /api/reports/<type>.
+var reportQueries = map[string]bool{
+	"insights":    true,
+	"funnel":      true,
+	"retention":   true,
+	"utm":         true,
+	"goals":       true,
+	"journey":     true,
+	"revenue":     true,
+	"attribution": true,
+}
+
+// GroupOf returns the endpoint group of a request URL.
+// Paths are matched by suffix so that Umami deployments under a sub-path are classified alike.
+func GroupOf(endpoint string) Group {
+	path := endpoint
+	if u, err := url.Parse(endpoint); err == nil {
+		path = u.Path
+	}
+	path = strings.TrimSuffix(path, "/")
+
+	const reports = "/api/reports/"
+	i := strings.LastIndex(path, reports)
+
+	switch {
+	case strings.HasSuffix(path, "/api/send") || strings.HasSuffix(path, "/api/batch"):
+		return GroupTracking
+	case i >= 0 && reportQueries[path[i+len(reports):]]:
+		return GroupReports
+	default:
+		return GroupDefault
+	}
+}
+
+// limiter enforces the configured rate and in-flight limits per endpoint group.
+type limiter struct {
+	buckets map[Group]*bucket
+	slots   map[Group]chan struct{}
+}
+
+func newLimiter() *limiter {
+	return &limiter{
+		buckets: map[Group]*bucket{},
+		slots:   map[Group]chan struct{}{},
+	}
+}
+
+func (l *limiter) setRate(rps float64, burst int, groups []Group) {
+	if len(groups) == 0 {
+		groups = allGroups
+	}
+	for _, g := range groups {
+		if rps <= 0 {
+			delete(l.buckets, g)
+			continue
+		}
+		l.buckets[g] = newBucket(rps, burst)
+	}
+}
+
+func (l *limiter) setMaxInFlight(n int, groups []Group) {
+	if len(groups) == 0 {
+		groups = allGroups
+	}
+	for _, g := range groups {
+		if n <= 0 {
+			delete(l.slots, g)
+			continue
+		}
+		l.slots[g] = make(chan struct{}, n)
+	}
+}
+
+// acquire waits for both a rate token and an in-flight slot of the group.
+// The returned func releases the slot and must be called once the response is consumed.
+func (l *limiter) acquire(ctx context.Context, g Group) (func(), error) {
+	if b, ok := l.buckets[g]; ok {
+		if err := b.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	slots, ok := l.slots[g]
+	if !ok {
+		return func() {}, nil
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// bucket is a token bucket refilled continuously at rate tokens per second.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rps float64, burst int) *bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{
+		rate:   rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+func (b *bucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+
+	var d time.Duration
+	if b.tokens < 0 {
+		d = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if err := sleep(ctx, d); err != nil {
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
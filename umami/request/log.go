@@ -0,0 +1,127 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+// sensitiveHeaders are the credential headers set by the auth package.
+var sensitiveHeaders = []string{"Authorization", "x-umami-api-key"}
+
+// LogLevels sets the level of each kind of request log record.
+type LogLevels struct {
+	// Success is used for requests that completed with a 2xx response.
+	Success slog.Level
+	// Retry is used for failed attempts that are about to be retried.
+	Retry slog.Level
+	// Failure is used for requests that failed for good.
+	Failure slog.Level
+}
+
+// DefaultLogLevels logs successful requests at debug, retries at warn and failures at error level.
+func DefaultLogLevels() LogLevels {
+	return LogLevels{
+		Success: slog.LevelDebug,
+		Retry:   slog.LevelWarn,
+		Failure: slog.LevelError,
+	}
+}
+
+// logAttempt records the outcome of a single attempt. Credentials are never logged:
+// auth headers are redacted and password fields are masked in the payload.
+func (c *client) logAttempt(ctx context.Context, req Request, body []byte, attempt, status int, latency time.Duration, retry bool, err error) {
+	if c.logger == nil {
+		return
+	}
+
+	level, msg := c.logLevels.Success, "umami request"
+	switch {
+	case retry:
+		level, msg = c.logLevels.Retry, "umami request failed, retrying"
+	case err != nil:
+		level, msg = c.logLevels.Failure, "umami request failed"
+	}
+	if !c.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("endpoint", stripQuery(req.Endpoint)),
+		slog.Any("query", req.Query),
+		slog.Int("status", status),
+		slog.Duration("latency", latency),
+		slog.Int("attempt", attempt),
+		slog.Any("headers", c.redactHeaders(req)),
+	}
+	if body != nil && c.logger.Enabled(ctx, slog.LevelDebug) {
+		attrs = append(attrs, slog.String("payload", redactPayload(body)))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	c.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+func (c *client) redactHeaders(req Request) map[string]string {
+	headers := make(map[string]string, len(req.Headers)+1)
+	for k, v := range req.Headers {
+		headers[k] = v
+		for _, h := range sensitiveHeaders {
+			if strings.EqualFold(k, h) {
+				headers[k] = redacted
+			}
+		}
+	}
+	if !req.Public && c.auth.Header() != "" {
+		headers[c.auth.Header()] = redacted
+	}
+	return headers
+}
+
+// redactPayload masks every JSON field whose name contains "password", such as the GetToken login payload.
+func redactPayload(body []byte) string {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return redacted
+	}
+	b, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return redacted
+	}
+	return string(b)
+}
+
+func redactValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if strings.Contains(strings.ToLower(k), "password") {
+				t[k] = redacted
+				continue
+			}
+			t[k] = redactValue(val)
+		}
+	case []any:
+		for i, val := range t {
+			t[i] = redactValue(val)
+		}
+	}
+	return v
+}
+
+func stripQuery(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	u.RawQuery = ""
+	return u.String()
+}
@@ -0,0 +1,51 @@
+package request
+
+import "context"
+
+// Handler sends a request and decodes the response into res.
+type Handler func(ctx context.Context, req Request, res any) error
+
+// Interceptor wraps the execution of every request sent through Client.Send.
+//
+// An interceptor may alter req before calling next, inspect or replace the decoded
+// res after next returns, or short-circuit the call entirely by not calling next.
+type Interceptor interface {
+	Intercept(ctx context.Context, req Request, res any, next Handler) error
+}
+
+// InterceptorFunc adapts a function to the Interceptor interface.
+type InterceptorFunc func(ctx context.Context, req Request, res any, next Handler) error
+
+func (f InterceptorFunc) Intercept(ctx context.Context, req Request, res any, next Handler) error {
+	return f(ctx, req, res, next)
+}
+
+// Before returns an interceptor running fn before the request is sent.
+// Returning an error aborts the request.
+func Before(fn func(ctx context.Context, req *Request) error) Interceptor {
+	return InterceptorFunc(func(ctx context.Context, req Request, res any, next Handler) error {
+		if err := fn(ctx, &req); err != nil {
+			return err
+		}
+		return next(ctx, req, res)
+	})
+}
+
+// After returns an interceptor running fn once the response has been decoded into res.
+// The error returned by fn replaces the request error.
+func After(fn func(ctx context.Context, req Request, res any, err error) error) Interceptor {
+	return InterceptorFunc(func(ctx context.Context, req Request, res any, next Handler) error {
+		return fn(ctx, req, res, next(ctx, req, res))
+	})
+}
+
+// chain wraps h with the interceptors, the first one being the outermost.
+func chain(h Handler, interceptors []Interceptor) Handler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		ic, next := interceptors[i], h
+		h = func(ctx context.Context, req Request, res any) error {
+			return ic.Intercept(ctx, req, res, next)
+		}
+	}
+	return h
+}
@@ -0,0 +1,142 @@
+package request
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how failed requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry; it doubles on every further attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential backoff.
+	MaxDelay time.Duration
+	// MaxRetryAfter is the longest Retry-After the server may request; defaults to MaxDelay when zero.
+	// A longer Retry-After stops the retries and the error is returned.
+	MaxRetryAfter time.Duration
+	// Jitter is the fraction (0-1) of each delay that is randomised.
+	Jitter float64
+	// ShouldRetry, when set, decides whether the failed attempt (starting at 1) is retried.
+	// It is only consulted for requests that are safe to retry.
+	ShouldRetry func(req Request, attempt int, err error) bool
+}
+
+// DefaultRetryPolicy returns a policy making up to 3 attempts with exponential backoff
+// starting at 200ms and capped at 5s, honouring a Retry-After of up to 30s.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:   3,
+		BaseDelay:     200 * time.Millisecond,
+		MaxDelay:      5 * time.Second,
+		MaxRetryAfter: 30 * time.Second,
+		Jitter:        0.2,
+	}
+}
+
+// IsRetryable reports whether err is a transient failure: a network error, a 429 or a 502/503/504 response.
+func IsRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func (p RetryPolicy) retry(req Request, attempt int, err error) bool {
+	if attempt >= p.MaxAttempts || !req.retryable() {
+		return false
+	}
+	if retryAfter(err) > p.retryAfterLimit() {
+		return false
+	}
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(req, attempt, err)
+	}
+	return IsRetryable(err)
+}
+
+// delay returns the wait before the next attempt, preferring the server's Retry-After when longer.
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+
+	if ra := retryAfter(err); ra > d {
+		d = ra
+	}
+	return d
+}
+
+func (p RetryPolicy) retryAfterLimit() time.Duration {
+	if p.MaxRetryAfter > 0 {
+		return p.MaxRetryAfter
+	}
+	return p.MaxDelay
+}
+
+// retryAfter returns the delay requested by the server for err, or 0 when none was sent.
+func retryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.RetryAfter
+	}
+	return 0
+}
+
+// retryable reports whether the request can be sent more than once without side effects.
+func (r Request) retryable() bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead || r.Idempotent
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,323 @@
+package scrub
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Pattern replaces every match of Regexp with Replacement.
+type Pattern struct {
+	Name        string
+	Regexp      *regexp.Regexp
+	Replacement string
+}
+
+// Segment replaces URL path segments fully matching Regexp with Replacement.
+type Segment struct {
+	Regexp      *regexp.Regexp
+	Replacement string
+}
+
+var (
+	// Email matches email addresses.
+	Email = Pattern{Name: "email", Regexp: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), Replacement: "[email]"}
+	// JWT matches JSON Web Tokens.
+	JWT = Pattern{Name: "jwt", Regexp: regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), Replacement: "[jwt]"}
+	// UUID matches UUIDs.
+	UUID = Pattern{Name: "uuid", Regexp: regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), Replacement: "[uuid]"}
+
+	// NumericID matches path segments made of digits, such as /users/123.
+	NumericID = Segment{Regexp: regexp.MustCompile(`^[0-9]+$`), Replacement: ":id"}
+	// UUIDSegment matches path segments holding a UUID.
+	UUIDSegment = Segment{Regexp: regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`), Replacement: ":id"}
+)
+
+// DefaultDropParams are the query parameters removed by Default.
+var DefaultDropParams = []string{
+	"token", "access_token", "id_token", "refresh_token", "code", "state",
+	"password", "passwd", "secret", "api_key", "apikey", "key", "auth",
+	"session", "sessionid", "session_id", "sid", "email",
+}
+
+// DefaultDropKeys are the event data keys removed by Default. The list is narrower than
+// DefaultDropParams, as names such as "state" or "code" are common in ordinary event data.
+var DefaultDropKeys = []string{
+	"token", "access_token", "id_token", "refresh_token",
+	"password", "passwd", "secret", "api_key", "apikey",
+}
+
+// Option configures a Scrubber.
+type Option func(*Scrubber)
+
+// WithDropParams removes the query parameters with the given names (case-insensitive).
+func WithDropParams(names ...string) Option {
+	return func(s *Scrubber) {
+		for _, n := range names {
+			s.drop[strings.ToLower(n)] = struct{}{}
+		}
+	}
+}
+
+// WithDropKeys removes the event data keys with the given names (case-insensitive), at any depth.
+func WithDropKeys(names ...string) Option {
+	return func(s *Scrubber) {
+		for _, n := range names {
+			s.dropKeys[strings.ToLower(n)] = struct{}{}
+		}
+	}
+}
+
+// WithHashParams replaces the values of the given query parameters and data keys with a salted
+// SHA-256 hash, keeping them comparable without revealing them.
+func WithHashParams(salt string, names ...string) Option {
+	return func(s *Scrubber) {
+		s.salt = salt
+		for _, n := range names {
+			s.hash[strings.ToLower(n)] = struct{}{}
+		}
+	}
+}
+
+// WithPatterns redacts the matches of the patterns in query values, fragments, titles and data strings.
+func WithPatterns(patterns ...Pattern) Option {
+	return func(s *Scrubber) {
+		s.patterns = append(s.patterns, patterns...)
+	}
+}
+
+// WithSegments replaces the URL path segments matching one of the rules.
+func WithSegments(segments ...Segment) Option {
+	return func(s *Scrubber) {
+		s.segments = append(s.segments, segments...)
+	}
+}
+
+// WithPathTemplates replaces the path segments of URLs matching a template such as
+// "/users/:id/orders/:order" with the template's placeholders.
+func WithPathTemplates(templates ...string) Option {
+	return func(s *Scrubber) {
+		for _, t := range templates {
+			s.templates = append(s.templates, strings.Split(strings.Trim(t, "/"), "/"))
+		}
+	}
+}
+
+// Scrubber removes personal data from tracking payloads.
+type Scrubber struct {
+	drop      map[string]struct{}
+	dropKeys  map[string]struct{}
+	hash      map[string]struct{}
+	salt      string
+	patterns  []Pattern
+	segments  []Segment
+	templates [][]string
+}
+
+// New returns a Scrubber applying only the given options.
+func New(opts ...Option) *Scrubber {
+	s := &Scrubber{drop: map[string]struct{}{}, dropKeys: map[string]struct{}{}, hash: map[string]struct{}{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Default returns a Scrubber dropping DefaultDropParams and DefaultDropKeys, redacting emails, JWTs
+// and UUIDs, and replacing numeric and UUID path segments with ":id". Further options are applied
+// after those.
+func Default(opts ...Option) *Scrubber {
+	return New(append([]Option{
+		WithDropParams(DefaultDropParams...),
+		WithDropKeys(DefaultDropKeys...),
+		WithPatterns(Email, JWT, UUID),
+		WithSegments(NumericID, UUIDSegment),
+	}, opts...)...)
+}
+
+// Payload scrubs the URL, referrer, title and data of p. Data is copied rather than modified.
+func (s *Scrubber) Payload(p *types.SendEventPayload) {
+	p.URL = s.URL(p.URL)
+	p.Referrer = s.URL(p.Referrer)
+	p.Title = s.Text(p.Title)
+	if p.Data != nil {
+		p.Data = s.data(p.Data)
+	}
+}
+
+// URL scrubs an absolute URL or a path with an optional query and fragment.
+func (s *Scrubber) URL(raw string) string {
+	if raw == "" {
+		return raw
+	}
+
+	rest, fragment, hasFragment := strings.Cut(raw, "#")
+	rest, query, hasQuery := strings.Cut(rest, "?")
+
+	// Absolute URLs lose their user info, which may hold credentials.
+	prefix, path := "", rest
+	if u, err := url.Parse(rest); err == nil && u.Host != "" {
+		prefix = u.Scheme + "://" + u.Host
+		if u.Scheme == "" {
+			prefix = "//" + u.Host
+		}
+		path = u.EscapedPath()
+	}
+
+	out := prefix + s.path(path)
+	if hasQuery {
+		if q := s.query(query); q != "" {
+			out += "?" + q
+		}
+	}
+	if hasFragment {
+		// Fragments often carry OAuth tokens as parameters.
+		if strings.Contains(fragment, "=") {
+			fragment = s.query(fragment)
+		} else {
+			fragment = s.Text(fragment)
+		}
+		if fragment != "" {
+			out += "#" + fragment
+		}
+	}
+	return out
+}
+
+// Text redacts the pattern matches in a free-form string.
+func (s *Scrubber) Text(text string) string {
+	for _, p := range s.patterns {
+		text = p.Regexp.ReplaceAllString(text, p.Replacement)
+	}
+	return text
+}
+
+func (s *Scrubber) path(p string) string {
+	if p == "" {
+		return p
+	}
+	segments := strings.Split(p, "/")
+
+	for _, tpl := range s.templates {
+		trimmed := strings.Split(strings.Trim(p, "/"), "/")
+		if len(trimmed) != len(tpl) {
+			continue
+		}
+		match := true
+		for i, part := range tpl {
+			if !strings.HasPrefix(part, ":") && part != trimmed[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			out := "/" + strings.Join(tpl, "/")
+			if strings.HasSuffix(p, "/") && len(p) > 1 {
+				out += "/"
+			}
+			return out
+		}
+	}
+
+	for i, seg := range segments {
+		if seg == "" {
+			continue
+		}
+		segments[i] = s.Text(seg)
+		for _, rule := range s.segments {
+			if rule.Regexp.MatchString(seg) {
+				segments[i] = rule.Replacement
+				break
+			}
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// query scrubs a raw query string, keeping the order and encoding of the parameters it leaves alone.
+func (s *Scrubber) query(raw string) string {
+	params := strings.Split(raw, "&")
+	kept := params[:0]
+	for _, param := range params {
+		if param == "" {
+			continue
+		}
+		rawName, rawValue, hasValue := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(rawName)
+		if err != nil {
+			name = rawName
+		}
+		name = strings.ToLower(name)
+
+		if _, ok := s.drop[name]; ok {
+			continue
+		}
+		if !hasValue {
+			kept = append(kept, param)
+			continue
+		}
+
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			value = rawValue
+		}
+		scrubbed := s.Text(value)
+		if _, ok := s.hash[name]; ok {
+			scrubbed = s.hashValue(value)
+		}
+		if scrubbed != value {
+			rawValue = url.QueryEscape(scrubbed)
+		}
+		kept = append(kept, rawName+"="+rawValue)
+	}
+	return strings.Join(kept, "&")
+}
+
+func (s *Scrubber) hashValue(v string) string {
+	sum := sha256.Sum256([]byte(s.salt + v))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (s *Scrubber) data(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		name := strings.ToLower(k)
+		if _, ok := s.dropKeys[name]; ok {
+			continue
+		}
+		if _, ok := s.hash[name]; ok {
+			if str, isString := v.(string); isString {
+				out[k] = s.hashValue(str)
+				continue
+			}
+		}
+		out[k] = s.value(v)
+	}
+	return out
+}
+
+func (s *Scrubber) value(v any) any {
+	switch t := v.(type) {
+	case string:
+		return s.Text(t)
+	case map[string]any:
+		return s.data(t)
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			out[i] = s.value(item)
+		}
+		return out
+	case []string:
+		out := make([]string, len(t))
+		for i, item := range t {
+			out[i] = s.Text(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,108 @@
+package umami
+
+import (
+	"context"
+	"fmt"
+	"github.com/AdamShannag/umami-client/umami/api"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"slices"
+	"sync"
+	"time"
+)
+
+// SessionStore keeps the latest /api/send cache token of each visitor.
+type SessionStore interface {
+	// Load returns the cache token of the visitor, or "" when there is none.
+	Load(ctx context.Context, visitorID string) (string, error)
+	// Save stores the cache token of the visitor, replacing any previous one.
+	Save(ctx context.Context, visitorID, cache string) error
+}
+
+// MemorySessionStore keeps cache tokens in memory, forgetting those unused for longer than its TTL.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	tokens   map[string]sessionToken
+	lastScan time.Time
+}
+
+type sessionToken struct {
+	cache   string
+	savedAt time.Time
+}
+
+// NewMemorySessionStore returns a store forgetting tokens after ttl. Umami starts a new visit after
+// 30 minutes of inactivity, which makes it a sensible TTL; ttl <= 0 keeps tokens forever.
+func NewMemorySessionStore(ttl time.Duration) *MemorySessionStore {
+	return &MemorySessionStore{ttl: ttl, tokens: map[string]sessionToken{}, lastScan: time.Now()}
+}
+
+func (s *MemorySessionStore) Load(_ context.Context, visitorID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[visitorID]
+	if !ok || s.expired(t, time.Now()) {
+		return "", nil
+	}
+	return t.cache, nil
+}
+
+func (s *MemorySessionStore) Save(_ context.Context, visitorID, cache string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.tokens[visitorID] = sessionToken{cache: cache, savedAt: now}
+
+	// Drop expired tokens at most once per TTL so that the map does not grow with every visitor.
+	if s.ttl > 0 && now.Sub(s.lastScan) > s.ttl {
+		for id, t := range s.tokens {
+			if s.expired(t, now) {
+				delete(s.tokens, id)
+			}
+		}
+		s.lastScan = now
+	}
+	return nil
+}
+
+func (s *MemorySessionStore) expired(t sessionToken, now time.Time) bool {
+	return s.ttl > 0 && now.Sub(t.savedAt) > s.ttl
+}
+
+// Session sends the events of one visitor so that they land in a single Umami session: the cache
+// token returned by each send is saved in the store and replayed on the next one.
+type Session struct {
+	public    api.Public
+	store     SessionStore
+	visitorID string
+	userAgent string
+}
+
+// NewSession returns the session of the visitor identified by visitorID, e.g. your user ID.
+// Events are sent through public with the visitor's User-Agent.
+func NewSession(public api.Public, store SessionStore, visitorID, userAgent string) *Session {
+	return &Session{public: public, store: store, visitorID: visitorID, userAgent: userAgent}
+}
+
+// Send sends the event in the visitor's session. A failing store does not prevent the send: without
+// a cache token, Umami resolves the session itself. When saving the new token fails, the event has
+// been recorded and the returned error wraps the store error.
+func (s *Session) Send(ctx context.Context, payload types.SendEventRequest, opts ...api.SendOption) (types.SendResponse, error) {
+	if cache, err := s.store.Load(ctx, s.visitorID); err == nil && cache != "" {
+		opts = append(slices.Clip(opts), api.WithCache(cache))
+	}
+
+	resp, err := s.public.Send(ctx, s.userAgent, payload, opts...)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.Cache != "" {
+		if err = s.store.Save(ctx, s.visitorID, resp.Cache); err != nil {
+			return resp, fmt.Errorf("save session cache: %w", err)
+		}
+	}
+	return resp, nil
+}
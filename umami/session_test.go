@@ -0,0 +1,53 @@
+package umami
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSession_ReplaysCacheToken(t *testing.T) {
+	var calls int
+	var caches []string
+	c := newMockClient(func(r *http.Request) *http.Response {
+		calls++
+		caches = append(caches, r.Header.Get("x-umami-cache"))
+		assertEqual(t, r.Header.Get("User-Agent"), "TestAgent")
+		return mockJSONResp([]byte(fmt.Sprintf(`{"cache":"tok%d","sessionId":"s1","visitId":"v1"}`, calls)))
+	})
+
+	store := NewMemorySessionStore(30 * time.Minute)
+	alice := NewSession(c.Public(), store, "alice", "TestAgent")
+	bob := NewSession(c.Public(), store, "bob", "TestAgent")
+
+	resp, err := alice.Send(context.Background(), testEvent)
+	assertNil(t, err)
+	assertEqual(t, resp.SessionID, "s1")
+
+	_, err = alice.Send(context.Background(), testEvent)
+	assertNil(t, err)
+	_, err = bob.Send(context.Background(), testEvent)
+	assertNil(t, err)
+
+	assertEqual(t, fmt.Sprint(caches), "[ tok1 ]")
+
+	cache, err := store.Load(context.Background(), "alice")
+	assertNil(t, err)
+	assertEqual(t, cache, "tok2")
+}
+
+func TestMemorySessionStore_Expires(t *testing.T) {
+	store := NewMemorySessionStore(time.Millisecond)
+	assertNil(t, store.Save(context.Background(), "alice", "tok"))
+
+	time.Sleep(5 * time.Millisecond)
+
+	cache, err := store.Load(context.Background(), "alice")
+	assertNil(t, err)
+	assertEqual(t, cache, "")
+
+	assertNil(t, store.Save(context.Background(), "bob", "tok"))
+	assertEqual(t, len(store.tokens), 1)
+}
@@ -0,0 +1,171 @@
+package spool
+
+import (
+	"context"
+	"errors"
+	"github.com/AdamShannag/umami-client/umami/api"
+	"github.com/AdamShannag/umami-client/umami/request"
+	"github.com/AdamShannag/umami-client/umami/scrub"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"slices"
+	"time"
+)
+
+// Undeliverable reports whether a send failed because Umami is unavailable, in which case the
+// event is spooled: a network error, a 429 or a 5xx response.
+func Undeliverable(err error) bool {
+	return request.IsRetryable(err) || errors.Is(err, request.ErrServer)
+}
+
+// Public wraps an api.Public, spooling the events that cannot be delivered and sending them again
+// through Flush or Run. While the spool holds events, new events are spooled too so that they are
+// delivered in order.
+type Public struct {
+	api.Public
+	spool    *Spool
+	onDrop   func(e Entry, err error)
+	scrubber *scrub.Scrubber
+}
+
+// PublicOption configures a Public.
+type PublicOption func(*Public)
+
+// WithDropHandler sets a function called with the spooled entries Umami rejects on replay,
+// e.g. with a 400 response; they are removed from the spool.
+func WithDropHandler(fn func(e Entry, err error)) PublicOption {
+	return func(p *Public) {
+		p.onDrop = fn
+	}
+}
+
+// WithScrubber scrubs events before they are sent or written to disk, so that the spool never
+// holds the personal data removed by sc. Use it instead of the client's WithScrubber, since
+// hashed values would be hashed again.
+func WithScrubber(sc *scrub.Scrubber) PublicOption {
+	return func(p *Public) {
+		p.scrubber = sc
+	}
+}
+
+// NewPublic returns public backed by the spool s.
+func NewPublic(public api.Public, s *Spool, opts ...PublicOption) *Public {
+	p := &Public{Public: public, spool: s}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Send sends the event, or spools it when Umami is unavailable; a spooled event is not an error,
+// and its response is empty. Errors of Append, such as ErrFull, are returned joined with the send error.
+func (p *Public) Send(ctx context.Context, userAgent string, payload types.SendEventRequest, opts ...api.SendOption) (types.SendResponse, error) {
+	if p.scrubber != nil {
+		p.scrubber.Payload(&payload.Payload)
+	}
+
+	var sendErr error
+	if p.spool.Depth() == 0 {
+		resp, err := p.Public.Send(ctx, userAgent, payload, opts...)
+		if err == nil || !Undeliverable(err) {
+			return resp, err
+		}
+		sendErr = err
+	}
+
+	e := Entry{UserAgent: userAgent, Event: payload, Options: api.NewSendOptions(opts...)}
+	if err := p.spool.Append(e); err != nil {
+		return types.SendResponse{}, errors.Join(sendErr, err)
+	}
+	return types.SendResponse{}, nil
+}
+
+// SendBatch sends the events and spools those that could not be delivered because Umami is
+// unavailable; all of them while the spool holds events. Events recorded or rejected individually
+// are reported in the results as usual.
+func (p *Public) SendBatch(ctx context.Context, userAgent string, events []types.SendEventRequest, opts ...api.SendOption) ([]types.BatchItemResult, error) {
+	if p.scrubber != nil {
+		events = slices.Clone(events)
+		for i := range events {
+			p.scrubber.Payload(&events[i].Payload)
+		}
+	}
+
+	var results []types.BatchItemResult
+	var sendErr error
+	if p.spool.Depth() == 0 {
+		var err error
+		results, err = p.Public.SendBatch(ctx, userAgent, events, opts...)
+		if err == nil || !Undeliverable(err) {
+			return results, err
+		}
+		sendErr = err
+	}
+
+	// Without a result per event, none of them is known to be delivered.
+	if len(results) != len(events) {
+		results = make([]types.BatchItemResult, len(events))
+		for i := range results {
+			results[i] = types.BatchItemResult{Index: i, Err: sendErr}
+		}
+	}
+
+	o := api.NewSendOptions(opts...)
+	var appendErr error
+	for i, event := range events {
+		itemErr := results[i].Err
+		if sendErr != nil && !Undeliverable(itemErr) {
+			continue
+		}
+		results[i].Err = nil
+		if err := p.spool.Append(Entry{UserAgent: userAgent, Event: event, Options: o}); err != nil {
+			results[i].Err = errors.Join(itemErr, err)
+			if appendErr == nil {
+				appendErr = results[i].Err
+			}
+		}
+	}
+	return results, appendErr
+}
+
+// Flush replays the spooled events in order, returning how many were delivered. It stops at the
+// first event that is still undeliverable; events Umami rejects are dropped.
+func (p *Public) Flush(ctx context.Context) (int, error) {
+	return p.spool.Replay(ctx, func(ctx context.Context, e Entry) error {
+		_, err := p.Public.Send(ctx, e.UserAgent, e.Event, e.sendOptions()...)
+		if err != nil && !Undeliverable(err) {
+			if p.onDrop != nil {
+				p.onDrop(e, err)
+			}
+			return nil
+		}
+		return err
+	})
+}
+
+// Run flushes the spool every interval until ctx is done.
+func (p *Public) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if p.spool.Depth() > 0 {
+				_, _ = p.Flush(ctx)
+			}
+		}
+	}
+}
+
+func (e Entry) sendOptions() []api.SendOption {
+	opts := []api.SendOption{
+		api.WithClientIP(e.Options.ClientIP),
+		api.WithAcceptLanguage(e.Options.AcceptLanguage),
+	}
+	for k, v := range e.Options.Headers {
+		opts = append(opts, api.WithHeader(k, v))
+	}
+	return opts
+}
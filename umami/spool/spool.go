@@ -0,0 +1,516 @@
+package spool
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/AdamShannag/umami-client/umami/api"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrFull is returned by Append when the entry would exceed the maximum spool size.
+	ErrFull = errors.New("spool: full")
+	// ErrClosed is returned once the spool has been closed.
+	ErrClosed = errors.New("spool: closed")
+)
+
+const (
+	segmentExt = ".seg"
+	cursorFile = "cursor.json"
+	// headerSize is the size of a record header: the data length and its CRC-32, both big-endian uint32.
+	headerSize = 8
+	// maxRecordSize bounds the length read from a record header, which may be corrupted.
+	maxRecordSize = 16 << 20
+)
+
+// Entry is a spooled event along with what is needed to send it again.
+type Entry struct {
+	UserAgent string                 `json:"userAgent"`
+	Event     types.SendEventRequest `json:"event"`
+	Options   api.SendOptions        `json:"options"`
+}
+
+// Option configures a Spool.
+type Option func(*Spool)
+
+// WithSegmentSize sets the size after which a new segment file is started (default 4 MiB).
+func WithSegmentSize(bytes int64) Option {
+	return func(s *Spool) {
+		if bytes > 0 {
+			s.segmentSize = bytes
+		}
+	}
+}
+
+// WithMaxSize caps the bytes of pending entries (default 256 MiB); Append returns ErrFull beyond it.
+// Zero removes the cap.
+func WithMaxSize(bytes int64) Option {
+	return func(s *Spool) {
+		if bytes >= 0 {
+			s.maxSize = bytes
+		}
+	}
+}
+
+// cursor is the position of the next entry to replay.
+type cursor struct {
+	Segment int64 `json:"segment"`
+	Offset  int64 `json:"offset"`
+}
+
+// Spool is a write-ahead log of events, stored in a directory as append-only segment files.
+// Each record holds a length and a CRC-32 checksum, so that a torn write at the end of the
+// log is discarded on Open and corrupted records are skipped on replay. The replay position
+// is persisted after each delivery, so entries survive process restarts and are replayed in
+// order. Delivery is at-least-once: the cursor is not synced to disk, so an entry delivered
+// just before a crash may be delivered again.
+type Spool struct {
+	dir         string
+	segmentSize int64
+	maxSize     int64
+
+	mu       sync.Mutex
+	segments []int64 // sequence numbers of the segment files, ascending; the last one is written to
+	w        *os.File
+	wSize    int64
+	cur      cursor
+	depth    int
+	size     int64
+	corrupt  int
+	closed   bool
+
+	// replayMu serializes replays, which release mu while delivering entries.
+	replayMu sync.Mutex
+}
+
+// Open opens the spool stored in dir, creating it if needed.
+func Open(dir string, opts ...Option) (*Spool, error) {
+	s := &Spool{
+		dir:         dir,
+		segmentSize: 4 << 20,
+		maxSize:     256 << 20,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create spool dir: %w", err)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Spool) load() error {
+	names, err := filepath.Glob(filepath.Join(s.dir, "*"+segmentExt))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		seq, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(name), segmentExt), 10, 64)
+		if err == nil {
+			s.segments = append(s.segments, seq)
+		}
+	}
+	slices.Sort(s.segments)
+
+	if data, err := os.ReadFile(filepath.Join(s.dir, cursorFile)); err == nil {
+		if err = json.Unmarshal(data, &s.cur); err != nil {
+			return fmt.Errorf("decode spool cursor: %w", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("read spool cursor: %w", err)
+	}
+
+	// Segments before the cursor have been replayed already.
+	for len(s.segments) > 0 && s.segments[0] < s.cur.Segment {
+		if err := os.Remove(s.segmentPath(s.segments[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("remove replayed segment: %w", err)
+		}
+		s.segments = s.segments[1:]
+	}
+	if len(s.segments) == 0 || s.segments[0] > s.cur.Segment {
+		s.cur = cursor{Segment: max(s.cur.Segment, 1)}
+		if len(s.segments) > 0 {
+			s.cur.Segment = s.segments[0]
+		}
+	}
+	if len(s.segments) == 0 {
+		s.segments = []int64{s.cur.Segment}
+	}
+
+	for i, seq := range s.segments {
+		offset := int64(0)
+		if seq == s.cur.Segment {
+			offset = s.cur.Offset
+		}
+		valid, count, err := scanSegment(s.segmentPath(seq), offset)
+		if err != nil {
+			return err
+		}
+		s.depth += count
+		s.size += valid - offset
+
+		if i == len(s.segments)-1 {
+			return s.openWriter(seq, valid)
+		}
+	}
+	return nil
+}
+
+// scanSegment counts the valid records of a segment from offset, returning the offset following
+// the last one. A missing segment is empty.
+func scanSegment(path string, offset int64) (int64, int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return offset, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("open segment: %w", err)
+	}
+	defer f.Close()
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return 0, 0, fmt.Errorf("seek segment: %w", err)
+	}
+
+	count := 0
+	for {
+		n, err := skipRecord(f)
+		if err != nil {
+			return offset, count, nil
+		}
+		offset += n
+		count++
+	}
+}
+
+// openWriter opens the active segment for appending, discarding whatever follows its valid records.
+func (s *Spool) openWriter(seq, size int64) error {
+	f, err := os.OpenFile(s.segmentPath(seq), os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open segment: %w", err)
+	}
+	if err = f.Truncate(size); err == nil {
+		_, err = f.Seek(size, io.SeekStart)
+	}
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("truncate segment: %w", err)
+	}
+	s.w, s.wSize = f, size
+	return nil
+}
+
+func (s *Spool) segmentPath(seq int64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+// Append persists an entry at the end of the spool. An event without a timestamp is stamped with
+// the current time, so that it is recorded when it happened rather than when it is replayed.
+func (s *Spool) Append(e Entry) error {
+	if e.Event.Payload.Timestamp == 0 {
+		e.Event.Payload.Timestamp = time.Now().Unix()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal spool entry: %w", err)
+	}
+	if len(data) > maxRecordSize {
+		return fmt.Errorf("spool entry of %d bytes exceeds %d bytes", len(data), maxRecordSize)
+	}
+	rec := make([]byte, headerSize+len(data))
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.ChecksumIEEE(data))
+	copy(rec[headerSize:], data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if s.maxSize > 0 && s.size+int64(len(rec)) > s.maxSize {
+		return ErrFull
+	}
+
+	if s.wSize > 0 && s.wSize+int64(len(rec)) > s.segmentSize {
+		if err = s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err = s.w.Write(rec); err == nil {
+		err = s.w.Sync()
+	}
+	if err != nil {
+		// Drop the partial record so that the next append starts on a record boundary.
+		_ = s.w.Truncate(s.wSize)
+		_, _ = s.w.Seek(s.wSize, io.SeekStart)
+		return fmt.Errorf("write spool entry: %w", err)
+	}
+
+	s.wSize += int64(len(rec))
+	s.size += int64(len(rec))
+	s.depth++
+	return nil
+}
+
+func (s *Spool) rotate() error {
+	if err := s.w.Close(); err != nil {
+		return fmt.Errorf("close segment: %w", err)
+	}
+	seq := s.segments[len(s.segments)-1] + 1
+	if err := s.openWriter(seq, 0); err != nil {
+		return err
+	}
+	s.segments = append(s.segments, seq)
+	return nil
+}
+
+// Replay delivers the pending entries in order through fn, stopping at the first error, which it
+// returns along with the number of entries delivered. An entry is removed from the spool once fn
+// returns nil for it; fn must therefore return nil for entries it gives up on.
+func (s *Spool) Replay(ctx context.Context, fn func(ctx context.Context, e Entry) error) (int, error) {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	delivered := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return delivered, err
+		}
+
+		e, next, ok, err := s.next()
+		if err != nil || !ok {
+			return delivered, err
+		}
+
+		if err = fn(ctx, e); err != nil {
+			return delivered, err
+		}
+
+		if err = s.advance(next); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+}
+
+// next reads the entry at the cursor, moving past finished and corrupted segments.
+func (s *Spool) next() (Entry, cursor, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return Entry{}, cursor{}, false, ErrClosed
+	}
+
+	for {
+		active := s.cur.Segment == s.segments[len(s.segments)-1]
+		if active && s.cur.Offset >= s.wSize {
+			return Entry{}, cursor{}, false, nil
+		}
+
+		e, n, err := readEntry(s.segmentPath(s.cur.Segment), s.cur.Offset)
+		if err == nil {
+			return e, cursor{Segment: s.cur.Segment, Offset: s.cur.Offset + n}, true, nil
+		}
+		if errors.Is(err, errDecode) {
+			// The record is intact but not an entry: skip it alone.
+			s.corrupt++
+			if err = s.advanceLocked(cursor{Segment: s.cur.Segment, Offset: s.cur.Offset + n}); err != nil {
+				return Entry{}, cursor{}, false, err
+			}
+			continue
+		}
+		if active {
+			// Records of the active segment are fully written under mu, so this is a real failure.
+			return Entry{}, cursor{}, false, err
+		}
+
+		// End of a sealed segment, or a corrupted record whose length cannot be trusted:
+		// the rest of the segment is skipped. At the end of a segment the depth and size are
+		// already exact, so the remaining segments are only rescanned after a corruption.
+		corrupted := !errors.Is(err, io.EOF)
+		if err = s.dropSegment(); err != nil {
+			return Entry{}, cursor{}, false, err
+		}
+		if corrupted {
+			s.corrupt++
+			s.recount()
+		}
+	}
+}
+
+// dropSegment removes the segment at the cursor and moves the cursor to the next one.
+func (s *Spool) dropSegment() error {
+	old := s.cur.Segment
+
+	s.segments = s.segments[1:]
+	s.cur = cursor{Segment: s.segments[0]}
+	if err := s.saveCursor(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.segmentPath(old)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove replayed segment: %w", err)
+	}
+	return nil
+}
+
+// recount recomputes the depth and size after records were skipped.
+func (s *Spool) recount() {
+	s.depth, s.size = 0, 0
+	for _, seq := range s.segments {
+		offset := int64(0)
+		if seq == s.cur.Segment {
+			offset = s.cur.Offset
+		}
+		valid, count, _ := scanSegment(s.segmentPath(seq), offset)
+		s.depth += count
+		s.size += valid - offset
+	}
+}
+
+func (s *Spool) advance(next cursor) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	return s.advanceLocked(next)
+}
+
+func (s *Spool) advanceLocked(next cursor) error {
+	s.size -= next.Offset - s.cur.Offset
+	s.cur = next
+	s.depth--
+	return s.saveCursor()
+}
+
+func (s *Spool) saveCursor() error {
+	data, err := json.Marshal(s.cur)
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.dir, cursorFile+".tmp")
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write spool cursor: %w", err)
+	}
+	if err = os.Rename(tmp, filepath.Join(s.dir, cursorFile)); err != nil {
+		return fmt.Errorf("replace spool cursor: %w", err)
+	}
+	return nil
+}
+
+// Depth returns the number of entries waiting to be replayed.
+func (s *Spool) Depth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.depth
+}
+
+// Size returns the bytes of the entries waiting to be replayed.
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Corrupted returns the number of corrupted records skipped by Replay since Open.
+func (s *Spool) Corrupted() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.corrupt
+}
+
+// Close closes the active segment; pending entries are replayed after the next Open.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.w.Close()
+}
+
+// readEntry reads the record at offset of a segment, returning it and its size.
+func readEntry(path string, offset int64) (Entry, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Entry{}, 0, fmt.Errorf("open segment: %w", err)
+	}
+	defer f.Close()
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return Entry{}, 0, fmt.Errorf("seek segment: %w", err)
+	}
+
+	data, err := readRecord(f)
+	if err != nil {
+		return Entry{}, 0, err
+	}
+
+	n := int64(headerSize + len(data))
+	var e Entry
+	if err = json.Unmarshal(data, &e); err != nil {
+		return Entry{}, n, fmt.Errorf("%w: %w", errDecode, err)
+	}
+	return e, n, nil
+}
+
+var (
+	errChecksum = errors.New("spool: checksum mismatch")
+	errDecode   = errors.New("spool: undecodable entry")
+)
+
+// readRecord reads one record, returning io.EOF at the end of the segment and an error
+// for a truncated or corrupted record.
+func readRecord(r io.Reader) ([]byte, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("read record header: %w", err)
+	}
+
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxRecordSize {
+		return nil, errChecksum
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("read record: %w", io.ErrUnexpectedEOF)
+	}
+	if crc32.ChecksumIEEE(data) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errChecksum
+	}
+	return data, nil
+}
+
+func skipRecord(r io.Reader) (int64, error) {
+	data, err := readRecord(r)
+	if err != nil {
+		return 0, err
+	}
+	return int64(headerSize + len(data)), nil
+}
@@ -0,0 +1,376 @@
+package spool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/AdamShannag/umami-client/umami/api"
+	"github.com/AdamShannag/umami-client/umami/internal/publictest"
+	"github.com/AdamShannag/umami-client/umami/request"
+	"github.com/AdamShannag/umami-client/umami/scrub"
+	"github.com/AdamShannag/umami-client/umami/spool"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func entry(i int) spool.Entry {
+	return spool.Entry{
+		UserAgent: "ua",
+		Event:     types.SendEventRequest{Type: "event", Payload: types.SendEventPayload{Website: "w1", URL: fmt.Sprintf("/%d", i)}},
+	}
+}
+
+func mustOpen(t *testing.T, dir string, opts ...spool.Option) *spool.Spool {
+	t.Helper()
+	s, err := spool.Open(dir, opts...)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func appendN(t *testing.T, s *spool.Spool, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		if err := s.Append(entry(i)); err != nil {
+			t.Fatalf("append %d: %v", i, err)
+		}
+	}
+}
+
+// replayAll returns the URLs of the replayed entries.
+func replayAll(t *testing.T, s *spool.Spool) []string {
+	t.Helper()
+	var urls []string
+	_, err := s.Replay(context.Background(), func(_ context.Context, e spool.Entry) error {
+		urls = append(urls, e.Event.Payload.URL)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	return urls
+}
+
+func segments(t *testing.T, dir string) []string {
+	t.Helper()
+	names, _ := filepath.Glob(filepath.Join(dir, "*.seg"))
+	return names
+}
+
+func TestSpool_ReplaysInOrderAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir, spool.WithSegmentSize(256))
+
+	appendN(t, s, 0, 10)
+	if s.Depth() != 10 || s.Size() == 0 {
+		t.Fatalf("unexpected depth %d, size %d", s.Depth(), s.Size())
+	}
+	if len(segments(t, dir)) < 2 {
+		t.Fatalf("expected several segments, got %d", len(segments(t, dir)))
+	}
+
+	got := fmt.Sprint(replayAll(t, s))
+	if got != "[/0 /1 /2 /3 /4 /5 /6 /7 /8 /9]" {
+		t.Errorf("unexpected replay order %s", got)
+	}
+	if s.Depth() != 0 || s.Size() != 0 {
+		t.Errorf("expected empty spool, got depth %d, size %d", s.Depth(), s.Size())
+	}
+	if n := len(segments(t, dir)); n != 1 {
+		t.Errorf("expected replayed segments to be removed, %d left", n)
+	}
+}
+
+func TestSpool_DepthAcrossSegmentRollover(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir, spool.WithSegmentSize(256))
+	appendN(t, s, 0, 10)
+
+	delivered, lastSize := 0, s.Size()
+	_, err := s.Replay(context.Background(), func(context.Context, spool.Entry) error {
+		// The entry being replayed is still pending.
+		if got := s.Depth(); got != 10-delivered {
+			t.Errorf("after %d entries: expected depth %d, got %d", delivered, 10-delivered, got)
+		}
+		if size := s.Size(); delivered > 0 && size >= lastSize {
+			t.Errorf("after %d entries: size %d did not shrink from %d", delivered, size, lastSize)
+		}
+		delivered, lastSize = delivered+1, s.Size()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered != 10 || s.Depth() != 0 || s.Size() != 0 {
+		t.Errorf("expected an empty spool after 10 entries, got %d delivered, depth %d, size %d", delivered, s.Depth(), s.Size())
+	}
+}
+
+func TestSpool_StopsOnErrorAndResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir, spool.WithSegmentSize(256))
+	appendN(t, s, 0, 6)
+
+	calls := 0
+	delivered, err := s.Replay(context.Background(), func(context.Context, spool.Entry) error {
+		if calls++; calls == 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	if delivered != 2 || err == nil {
+		t.Fatalf("expected 2 delivered and an error, got %d, %v", delivered, err)
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := mustOpen(t, dir, spool.WithSegmentSize(256))
+	if reopened.Depth() != 4 {
+		t.Fatalf("expected depth 4 after restart, got %d", reopened.Depth())
+	}
+	appendN(t, reopened, 6, 7)
+	if got := fmt.Sprint(replayAll(t, reopened)); got != "[/2 /3 /4 /5 /6]" {
+		t.Errorf("unexpected replay %s", got)
+	}
+}
+
+func TestSpool_DiscardsTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	appendN(t, s, 0, 2)
+	_ = s.Close()
+
+	f, err := os.OpenFile(segments(t, dir)[0], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte{0, 0, 0, 50, 1, 2})
+	_ = f.Close()
+
+	reopened := mustOpen(t, dir)
+	if reopened.Depth() != 2 {
+		t.Fatalf("expected depth 2, got %d", reopened.Depth())
+	}
+	appendN(t, reopened, 2, 3)
+	if got := fmt.Sprint(replayAll(t, reopened)); got != "[/0 /1 /2]" {
+		t.Errorf("unexpected replay %s", got)
+	}
+}
+
+func TestSpool_SkipsCorruptedSegment(t *testing.T) {
+	dir := t.TempDir()
+	s := mustOpen(t, dir, spool.WithSegmentSize(256))
+	appendN(t, s, 0, 6)
+	_ = s.Close()
+
+	first := segments(t, dir)[0]
+	data, _ := os.ReadFile(first)
+	data[len(data)-2] ^= 0xff
+	_ = os.WriteFile(first, data, 0o600)
+
+	reopened := mustOpen(t, dir, spool.WithSegmentSize(256))
+	urls := replayAll(t, reopened)
+	if len(urls) == 0 || len(urls) >= 6 || urls[len(urls)-1] != "/5" {
+		t.Errorf("expected the corrupted record to be skipped, got %v", urls)
+	}
+	if reopened.Corrupted() != 1 {
+		t.Errorf("expected 1 corrupted record, got %d", reopened.Corrupted())
+	}
+}
+
+func TestSpool_MaxSize(t *testing.T) {
+	s := mustOpen(t, t.TempDir(), spool.WithMaxSize(300))
+
+	var err error
+	for i := 0; err == nil && i < 100; i++ {
+		err = s.Append(entry(i))
+	}
+	if !errors.Is(err, spool.ErrFull) {
+		t.Fatalf("expected ErrFull, got %v", err)
+	}
+	if s.Size() > 300 {
+		t.Errorf("size %d exceeds the cap", s.Size())
+	}
+}
+
+// outage returns a fake Umami rejecting "/rejected" with a 400, and every event with a 503 while *down.
+func outage(down *bool) *publictest.Public {
+	return &publictest.Public{Err: func(event types.SendEventRequest) error {
+		if *down {
+			return &request.APIError{StatusCode: http.StatusServiceUnavailable}
+		}
+		if event.Payload.URL == "/rejected" {
+			return &request.APIError{StatusCode: http.StatusBadRequest}
+		}
+		return nil
+	}}
+}
+
+func TestPublic_SpoolsDuringOutage(t *testing.T) {
+	down := true
+	fake := outage(&down)
+	s := mustOpen(t, t.TempDir())
+	var dropped []string
+	public := spool.NewPublic(fake, s, spool.WithDropHandler(func(e spool.Entry, err error) {
+		dropped = append(dropped, e.Event.Payload.URL)
+	}))
+
+	for _, url := range []string{"/a", "/rejected", "/b"} {
+		event := types.SendEventRequest{Payload: types.SendEventPayload{URL: url}}
+		if _, err := public.Send(context.Background(), "ua", event, api.WithClientIP("203.0.113.7")); err != nil {
+			t.Fatalf("send %s: %v", url, err)
+		}
+	}
+	if s.Depth() != 3 {
+		t.Fatalf("expected 3 spooled events, got %d", s.Depth())
+	}
+
+	// Still down: nothing is delivered.
+	if n, err := public.Flush(context.Background()); n != 0 || err == nil {
+		t.Fatalf("expected flush to fail, got %d, %v", n, err)
+	}
+
+	down = false
+	n, err := public.Flush(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 entries flushed, got %d, %v", n, err)
+	}
+	if fmt.Sprint(fake.URLs()) != "[/a /b]" || fmt.Sprint(dropped) != "[/rejected]" {
+		t.Errorf("unexpected delivery: sent %v, dropped %v", fake.URLs(), dropped)
+	}
+
+	if _, err = public.Send(context.Background(), "ua", types.SendEventRequest{Payload: types.SendEventPayload{URL: "/c"}}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Depth() != 0 || fmt.Sprint(fake.URLs()) != "[/a /b /c]" {
+		t.Errorf("expected direct delivery once recovered, sent %v", fake.URLs())
+	}
+}
+
+func TestPublic_SendBatchSpoolsOnlyUndeliverable(t *testing.T) {
+	unavailable := &request.APIError{StatusCode: http.StatusServiceUnavailable}
+	fake := &publictest.Public{
+		BatchErr: unavailable,
+		Err: func(event types.SendEventRequest) error {
+			switch event.Payload.URL {
+			case "/rejected":
+				return &request.APIError{StatusCode: http.StatusBadRequest}
+			case "/down":
+				return unavailable
+			}
+			return nil
+		},
+	}
+	s := mustOpen(t, t.TempDir())
+	public := spool.NewPublic(fake, s)
+
+	var events []types.SendEventRequest
+	for _, url := range []string{"/a", "/rejected", "/down"} {
+		events = append(events, types.SendEventRequest{Payload: types.SendEventPayload{URL: url}})
+	}
+	results, err := public.SendBatch(context.Background(), "ua", events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil || results[2].Err != nil || results[1].Err == nil || spool.Undeliverable(results[1].Err) {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if s.Depth() != 1 {
+		t.Fatalf("expected only the undelivered event spooled, got %d", s.Depth())
+	}
+
+	fake.Err = nil
+	if n, err := public.Flush(context.Background()); err != nil || n != 1 {
+		t.Fatalf("expected 1 entry flushed, got %d, %v", n, err)
+	}
+	if fmt.Sprint(fake.URLs()) != "[/a /down]" {
+		t.Errorf("unexpected delivery: %v", fake.URLs())
+	}
+}
+
+func TestPublic_ScrubsBeforeSpooling(t *testing.T) {
+	down := true
+	fake := outage(&down)
+	dir := t.TempDir()
+	s := mustOpen(t, dir)
+	public := spool.NewPublic(fake, s, spool.WithScrubber(scrub.Default()))
+
+	event := types.SendEventRequest{Payload: types.SendEventPayload{URL: "/users/12?email=jane@example.com"}}
+	if _, err := public.Send(context.Background(), "ua", event); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := public.SendBatch(context.Background(), "ua", []types.SendEventRequest{event}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"))
+	for _, name := range segments {
+		data, _ := os.ReadFile(name)
+		if strings.Contains(string(data), "jane@example.com") {
+			t.Errorf("%s holds unscrubbed data", filepath.Base(name))
+		}
+	}
+	if event.Payload.URL != "/users/12?email=jane@example.com" {
+		t.Error("caller event was modified")
+	}
+
+	s = mustOpen(t, dir)
+	down = false
+	if _, err := spool.NewPublic(fake, s).Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(fake.URLs()) != "[/users/:id /users/:id]" {
+		t.Errorf("unexpected delivery: %v", fake.URLs())
+	}
+}
+
+func TestPublic_ReplayKeepsEventTime(t *testing.T) {
+	down := true
+	fake := outage(&down)
+	s := mustOpen(t, t.TempDir())
+	public := spool.NewPublic(fake, s)
+
+	before := time.Now().Unix()
+	for _, event := range []types.SendEventRequest{
+		{Payload: types.SendEventPayload{URL: "/now"}},
+		{Payload: types.SendEventPayload{URL: "/past", Timestamp: 1614600000}},
+	} {
+		if _, err := public.Send(context.Background(), "ua", event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after := time.Now().Unix()
+
+	down = false
+	if _, err := public.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls()
+	if ts := calls[0].Event.Payload.Timestamp; ts < before || ts > after {
+		t.Errorf("expected the spooling time between %d and %d, got %d", before, after, ts)
+	}
+	if ts := calls[1].Event.Payload.Timestamp; ts != 1614600000 {
+		t.Errorf("expected the explicit timestamp to be kept, got %d", ts)
+	}
+}
+
+func TestPublic_ReturnsPermanentErrors(t *testing.T) {
+	down := false
+	s := mustOpen(t, t.TempDir())
+
+	_, err := spool.NewPublic(outage(&down), s).Send(context.Background(), "ua", types.SendEventRequest{Payload: types.SendEventPayload{URL: "/rejected"}})
+	if err == nil || s.Depth() != 0 {
+		t.Errorf("expected the error to be returned without spooling, got %v, depth %d", err, s.Depth())
+	}
+}
@@ -0,0 +1,326 @@
+package umami
+
+import (
+	"context"
+	"errors"
+	"github.com/AdamShannag/umami-client/umami/api"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrTrackerClosed is returned by Tracker.Track once Close has been called.
+	ErrTrackerClosed = errors.New("umami: tracker closed")
+	// ErrQueueFull is returned by Tracker.Track when the event was dropped because the queue is full.
+	ErrQueueFull = errors.New("umami: tracker queue full")
+)
+
+// OverflowPolicy decides what Tracker.Track does when the queue is full.
+type OverflowPolicy int
+
+const (
+	// DropNewest drops the event being tracked and returns ErrQueueFull.
+	DropNewest OverflowPolicy = iota
+	// DropOldest drops the oldest queued event to make room.
+	DropOldest
+	// Block waits for room in the queue until the context passed to Track is done or the tracker is closed.
+	Block
+)
+
+// TrackerStats are the counters of a Tracker.
+type TrackerStats struct {
+	Enqueued uint64 // Events accepted by Track
+	Sent     uint64 // Events recorded by Umami
+	Failed   uint64 // Events whose send failed
+	Dropped  uint64 // Events dropped by the overflow policy or left over by Close
+	Queued   int    // Events currently waiting in the queue
+}
+
+// TrackerOption configures a Tracker.
+type TrackerOption func(*Tracker)
+
+// WithTrackerQueueSize sets the number of events the queue holds (default 1024).
+func WithTrackerQueueSize(n int) TrackerOption {
+	return func(t *Tracker) {
+		if n > 0 {
+			t.queueSize = n
+		}
+	}
+}
+
+// WithTrackerWorkers sets the number of goroutines sending events (default 2).
+func WithTrackerWorkers(n int) TrackerOption {
+	return func(t *Tracker) {
+		if n > 0 {
+			t.workers = n
+		}
+	}
+}
+
+// WithTrackerBatch makes workers send up to size events at once through SendBatch, waiting at most
+// linger for a batch to fill up. Events are sent one by one through Send by default.
+func WithTrackerBatch(size int, linger time.Duration) TrackerOption {
+	return func(t *Tracker) {
+		t.batchSize, t.linger = size, linger
+	}
+}
+
+// WithTrackerOverflow sets the policy applied when the queue is full (default DropNewest).
+func WithTrackerOverflow(policy OverflowPolicy) TrackerOption {
+	return func(t *Tracker) {
+		t.overflow = policy
+	}
+}
+
+// WithTrackerErrorHandler sets a function called with the events whose send failed.
+func WithTrackerErrorHandler(fn func(err error, events []types.SendEventRequest)) TrackerOption {
+	return func(t *Tracker) {
+		t.onError = fn
+	}
+}
+
+type trackedEvent struct {
+	userAgent string
+	event     types.SendEventRequest
+}
+
+// Tracker sends events asynchronously: Track only enqueues the event into a bounded in-memory
+// queue, which worker goroutines flush to Umami.
+type Tracker struct {
+	public    api.Public
+	queueSize int
+	workers   int
+	batchSize int
+	linger    time.Duration
+	overflow  OverflowPolicy
+	onError   func(err error, events []types.SendEventRequest)
+
+	queue chan trackedEvent
+
+	// stopping is closed first by Close to release the Track calls blocked on a full queue.
+	// mu guards closed; Track holds it for reading while enqueuing so that no event is
+	// enqueued once draining is closed and the workers may have drained the queue.
+	stopping  chan struct{}
+	closeOnce sync.Once
+	mu        sync.RWMutex
+	closed    bool
+	draining  chan struct{}
+	wg        sync.WaitGroup
+
+	// ctx is the context of the sends, cancelled when Close gives up draining.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	enqueued, sent, failed, dropped atomic.Uint64
+}
+
+// NewTracker starts a tracker sending events through public, usually Client.Public().
+func NewTracker(public api.Public, opts ...TrackerOption) *Tracker {
+	t := &Tracker{
+		public:    public,
+		queueSize: 1024,
+		workers:   2,
+		stopping:  make(chan struct{}),
+		draining:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	t.queue = make(chan trackedEvent, t.queueSize)
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+
+	t.wg.Add(t.workers)
+	for range t.workers {
+		go t.work()
+	}
+	return t
+}
+
+// Track enqueues an event sent with the given User-Agent. ctx only bounds the wait of the Block policy.
+func (t *Tracker) Track(ctx context.Context, userAgent string, event types.SendEventRequest) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return ErrTrackerClosed
+	}
+
+	e := trackedEvent{userAgent: userAgent, event: event}
+	for {
+		select {
+		case t.queue <- e:
+			t.enqueued.Add(1)
+			return nil
+		default:
+		}
+
+		switch t.overflow {
+		case DropOldest:
+			select {
+			case <-t.queue:
+				t.dropped.Add(1)
+			default:
+			}
+		case Block:
+			select {
+			case t.queue <- e:
+				t.enqueued.Add(1)
+				return nil
+			case <-ctx.Done():
+				t.dropped.Add(1)
+				return ctx.Err()
+			case <-t.stopping:
+				return ErrTrackerClosed
+			}
+		default:
+			t.dropped.Add(1)
+			return ErrQueueFull
+		}
+	}
+}
+
+// Stats returns the current counters.
+func (t *Tracker) Stats() TrackerStats {
+	return TrackerStats{
+		Enqueued: t.enqueued.Load(),
+		Sent:     t.sent.Load(),
+		Failed:   t.failed.Load(),
+		Dropped:  t.dropped.Load(),
+		Queued:   len(t.queue),
+	}
+}
+
+// Close stops accepting events and waits for the queued ones to be sent. When ctx is done first,
+// pending sends are cancelled, the remaining events are counted as dropped and ctx.Err() is returned.
+func (t *Tracker) Close(ctx context.Context) error {
+	first := false
+	t.closeOnce.Do(func() {
+		first = true
+		close(t.stopping)
+	})
+	if !first {
+		return nil
+	}
+
+	// No Track call blocks while holding mu once stopping is closed.
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+	close(t.draining)
+
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.cancel()
+		return nil
+	case <-ctx.Done():
+		t.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (t *Tracker) work() {
+	defer t.wg.Done()
+
+	for {
+		select {
+		case e := <-t.queue:
+			t.send(t.collect(e))
+		case <-t.draining:
+			for {
+				select {
+				case e := <-t.queue:
+					t.send(t.collect(e))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// collect returns first along with the events queued within the linger time, up to the batch size.
+func (t *Tracker) collect(first trackedEvent) []trackedEvent {
+	batch := []trackedEvent{first}
+	if t.batchSize <= 1 {
+		return batch
+	}
+
+	timer := time.NewTimer(t.linger)
+	defer timer.Stop()
+
+	for len(batch) < t.batchSize {
+		select {
+		case e := <-t.queue:
+			batch = append(batch, e)
+		case <-timer.C:
+			return batch
+		case <-t.draining:
+			// Draining: take what is queued without waiting.
+			select {
+			case e := <-t.queue:
+				batch = append(batch, e)
+			default:
+				return batch
+			}
+		}
+	}
+	return batch
+}
+
+func (t *Tracker) send(batch []trackedEvent) {
+	if t.ctx.Err() != nil {
+		t.dropped.Add(uint64(len(batch)))
+		return
+	}
+
+	if t.batchSize <= 1 {
+		for _, e := range batch {
+			_, err := t.public.Send(t.ctx, e.userAgent, e.event)
+			t.report(err, []types.SendEventRequest{e.event})
+		}
+		return
+	}
+
+	// Batched events share one User-Agent header per request.
+	var order []string
+	byAgent := map[string][]types.SendEventRequest{}
+	for _, e := range batch {
+		if _, ok := byAgent[e.userAgent]; !ok {
+			order = append(order, e.userAgent)
+		}
+		byAgent[e.userAgent] = append(byAgent[e.userAgent], e.event)
+	}
+
+	for _, ua := range order {
+		events := byAgent[ua]
+		results, err := t.public.SendBatch(t.ctx, ua, events)
+		if len(results) != len(events) {
+			t.report(err, events)
+			continue
+		}
+		for _, r := range results {
+			t.report(r.Err, events[r.Index:r.Index+1])
+		}
+	}
+}
+
+func (t *Tracker) report(err error, events []types.SendEventRequest) {
+	if err == nil {
+		t.sent.Add(uint64(len(events)))
+		return
+	}
+	t.failed.Add(uint64(len(events)))
+	if t.onError != nil {
+		t.onError(err, events)
+	}
+}
@@ -0,0 +1,162 @@
+package umami
+
+import (
+	"context"
+	"errors"
+	"github.com/AdamShannag/umami-client/umami/internal/publictest"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"testing"
+	"time"
+)
+
+func event(name string) types.SendEventRequest {
+	return types.SendEventRequest{Type: "event", Payload: types.SendEventPayload{Website: "w1", Name: name}}
+}
+
+func TestTracker_SendsAndDrainsOnClose(t *testing.T) {
+	public := &publictest.Public{}
+	tracker := NewTracker(public, WithTrackerWorkers(1))
+
+	for range 10 {
+		assertNil(t, tracker.Track(context.Background(), "ua", event("click")))
+	}
+	assertNil(t, tracker.Close(context.Background()))
+
+	assertEqual(t, len(public.Calls()), 10)
+	stats := tracker.Stats()
+	assertEqual(t, stats.Enqueued, uint64(10))
+	assertEqual(t, stats.Sent, uint64(10))
+	assertEqual(t, stats.Queued, 0)
+
+	if err := tracker.Track(context.Background(), "ua", event("late")); !errors.Is(err, ErrTrackerClosed) {
+		t.Errorf("expected ErrTrackerClosed, got %v", err)
+	}
+}
+
+func TestTracker_Overflow(t *testing.T) {
+	for _, tc := range []struct {
+		policy OverflowPolicy
+		err    error
+	}{
+		{DropNewest, ErrQueueFull},
+		{DropOldest, nil},
+	} {
+		public := &publictest.Public{Gate: make(chan struct{})}
+		tracker := NewTracker(public, WithTrackerWorkers(1), WithTrackerQueueSize(1), WithTrackerOverflow(tc.policy))
+
+		// The worker takes the first event and blocks on the gate; the second fills the queue.
+		assertNil(t, tracker.Track(context.Background(), "ua", event("1")))
+		for tracker.Stats().Queued != 0 {
+			time.Sleep(time.Millisecond)
+		}
+		assertNil(t, tracker.Track(context.Background(), "ua", event("2")))
+
+		if err := tracker.Track(context.Background(), "ua", event("3")); !errors.Is(err, tc.err) {
+			t.Errorf("policy %d: expected %v, got %v", tc.policy, tc.err, err)
+		}
+		assertEqual(t, tracker.Stats().Dropped, uint64(1))
+
+		close(public.Gate)
+		assertNil(t, tracker.Close(context.Background()))
+
+		want := "3"
+		if tc.policy == DropNewest {
+			want = "2"
+		}
+		assertEqual(t, public.Calls()[1].Event.Payload.Name, want)
+	}
+}
+
+func TestTracker_BlockHonoursContext(t *testing.T) {
+	public := &publictest.Public{Gate: make(chan struct{})}
+	tracker := NewTracker(public, WithTrackerWorkers(1), WithTrackerQueueSize(1), WithTrackerOverflow(Block))
+	defer close(public.Gate)
+
+	assertNil(t, tracker.Track(context.Background(), "ua", event("1")))
+	for tracker.Stats().Queued != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assertNil(t, tracker.Track(context.Background(), "ua", event("2")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := tracker.Track(ctx, "ua", event("3")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestTracker_CloseReleasesBlockedTrack(t *testing.T) {
+	public := &publictest.Public{Gate: make(chan struct{})}
+	tracker := NewTracker(public, WithTrackerWorkers(1), WithTrackerQueueSize(1), WithTrackerOverflow(Block))
+	defer close(public.Gate)
+
+	assertNil(t, tracker.Track(context.Background(), "ua", event("1")))
+	for tracker.Stats().Queued != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	assertNil(t, tracker.Track(context.Background(), "ua", event("2")))
+
+	blocked := make(chan error, 1)
+	go func() { blocked <- tracker.Track(context.Background(), "ua", event("3")) }()
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() { closed <- tracker.Close(ctx) }()
+
+	select {
+	case err := <-closed:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected deadline exceeded, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close did not honour its deadline")
+	}
+	if err := <-blocked; !errors.Is(err, ErrTrackerClosed) {
+		t.Errorf("expected ErrTrackerClosed, got %v", err)
+	}
+}
+
+func TestTracker_BatchesByUserAgent(t *testing.T) {
+	public := &publictest.Public{}
+	tracker := NewTracker(public, WithTrackerWorkers(1), WithTrackerBatch(10, time.Second))
+
+	for _, ua := range []string{"a", "b", "a"} {
+		assertNil(t, tracker.Track(context.Background(), ua, event("click")))
+	}
+	assertNil(t, tracker.Close(context.Background()))
+
+	batched := map[string]int{}
+	for _, c := range public.Calls() {
+		if c.Batch {
+			batched[c.UserAgent]++
+		}
+	}
+	assertEqual(t, batched["a"], 2)
+	assertEqual(t, batched["b"], 1)
+	assertEqual(t, tracker.Stats().Sent, uint64(3))
+}
+
+func TestTracker_CloseDeadline(t *testing.T) {
+	public := &publictest.Public{Gate: make(chan struct{})}
+	var failed int
+	tracker := NewTracker(public, WithTrackerWorkers(1), WithTrackerErrorHandler(func(err error, events []types.SendEventRequest) {
+		failed += len(events)
+	}))
+
+	for range 3 {
+		assertNil(t, tracker.Track(context.Background(), "ua", event("click")))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := tracker.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	stats := tracker.Stats()
+	assertEqual(t, stats.Failed, uint64(1))
+	assertEqual(t, stats.Dropped, uint64(2))
+	assertEqual(t, failed, 1)
+}
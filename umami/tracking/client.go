@@ -0,0 +1,173 @@
+package tracking
+
+import (
+	"context"
+	"fmt"
+	"github.com/AdamShannag/umami-client/umami/api"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"github.com/AdamShannag/umami-client/umami/validate"
+	"math"
+	"strings"
+)
+
+// Event types of /api/send.
+const (
+	TypeEvent    = "event"
+	TypeIdentify = "identify"
+)
+
+// Revenue data keys used by Umami's revenue report.
+const (
+	RevenueKey  = "revenue"
+	CurrencyKey = "currency"
+)
+
+// Visitor describes who triggered a tracking call.
+type Visitor struct {
+	UserAgent string
+	IP        string // Forwarded so that the visitor is geolocated, see api.WithClientIP
+	Language  string // e.g. "en-US"
+	Screen    string // e.g. "1920x1080"
+}
+
+// Page is where a tracking call happened.
+type Page struct {
+	URL      string // Path or absolute URL
+	Title    string
+	Referrer string
+	Hostname string // Defaults to the client hostname
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithHostname sets the hostname used when a Page does not set one.
+func WithHostname(hostname string) ClientOption {
+	return func(c *Client) {
+		c.hostname = hostname
+	}
+}
+
+// WithValidator sets the validator checking payloads before they are sent (default validate.Default()).
+func WithValidator(v validate.Validator) ClientOption {
+	return func(c *Client) {
+		c.validator = v
+	}
+}
+
+// Client builds and sends tracking calls for one website. Calls missing a required field, or
+// whose payload fails the validator, return an error wrapping validate.ErrInvalid without sending.
+type Client struct {
+	public    api.Public
+	websiteID string
+	hostname  string
+	validator validate.Validator
+}
+
+// NewClient returns a Client sending through public, usually umami.Client.Public().
+func NewClient(public api.Public, websiteID string, opts ...ClientOption) *Client {
+	c := &Client{public: public, websiteID: websiteID, validator: validate.Default()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// TrackPageview records a view of page.
+func (c *Client) TrackPageview(ctx context.Context, v Visitor, page Page) (types.SendResponse, error) {
+	if page.URL == "" {
+		return types.SendResponse{}, required("url")
+	}
+	return c.send(ctx, v, TypeEvent, c.payload(v, page))
+}
+
+// TrackEvent records a named event on page, with optional data.
+func (c *Client) TrackEvent(ctx context.Context, v Visitor, page Page, name string, data map[string]any) (types.SendResponse, error) {
+	if strings.TrimSpace(name) == "" {
+		return types.SendResponse{}, required("name")
+	}
+
+	payload := c.payload(v, page)
+	payload.Name, payload.Data = name, data
+	return c.send(ctx, v, TypeEvent, payload)
+}
+
+// Identify attaches a distinct ID and data to the visitor's session, such as a user ID and plan.
+func (c *Client) Identify(ctx context.Context, v Visitor, id string, data map[string]any) (types.SendResponse, error) {
+	if id == "" && len(data) == 0 {
+		return types.SendResponse{}, &validate.FieldError{Field: "id", Reason: "or data is required"}
+	}
+
+	payload := c.payload(v, Page{})
+	payload.ID, payload.Data = id, data
+	return c.send(ctx, v, TypeIdentify, payload)
+}
+
+// TrackRevenue records a named event carrying amount in currency, an ISO 4217 code such as "USD",
+// under the data keys read by Umami's revenue report. Other data keys are sent along.
+func (c *Client) TrackRevenue(ctx context.Context, v Visitor, page Page, name string, amount float64, currency string, data map[string]any) (types.SendResponse, error) {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return types.SendResponse{}, &validate.FieldError{Field: "data." + RevenueKey, Reason: "is not a finite number"}
+	}
+	if !isCurrencyCode(currency) {
+		return types.SendResponse{}, &validate.FieldError{Field: "data." + CurrencyKey, Reason: fmt.Sprintf("%q is not an ISO 4217 code", currency)}
+	}
+
+	merged := make(map[string]any, len(data)+2)
+	for k, val := range data {
+		merged[k] = val
+	}
+	merged[RevenueKey], merged[CurrencyKey] = amount, currency
+
+	return c.TrackEvent(ctx, v, page, name, merged)
+}
+
+func (c *Client) payload(v Visitor, page Page) types.SendEventPayload {
+	hostname := page.Hostname
+	if hostname == "" {
+		hostname = c.hostname
+	}
+	return types.SendEventPayload{
+		Website:  c.websiteID,
+		Hostname: hostname,
+		Language: v.Language,
+		Referrer: page.Referrer,
+		Screen:   v.Screen,
+		Title:    page.Title,
+		URL:      page.URL,
+	}
+}
+
+func (c *Client) send(ctx context.Context, v Visitor, eventType string, payload types.SendEventPayload) (types.SendResponse, error) {
+	if c.websiteID == "" {
+		return types.SendResponse{}, required("website")
+	}
+	if v.UserAgent == "" {
+		return types.SendResponse{}, required("user agent")
+	}
+	if err := c.validator.Validate(&payload); err != nil {
+		return types.SendResponse{}, err
+	}
+
+	var opts []api.SendOption
+	if v.IP != "" {
+		opts = append(opts, api.WithClientIP(v.IP))
+	}
+	return c.public.Send(ctx, v.UserAgent, types.SendEventRequest{Type: eventType, Payload: payload}, opts...)
+}
+
+func required(field string) error {
+	return &validate.FieldError{Field: field, Reason: "is required"}
+}
+
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
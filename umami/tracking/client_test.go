@@ -0,0 +1,149 @@
+package tracking_test
+
+import (
+	"context"
+	"errors"
+	"github.com/AdamShannag/umami-client/umami/internal/publictest"
+	"github.com/AdamShannag/umami-client/umami/tracking"
+	"github.com/AdamShannag/umami-client/umami/validate"
+	"math"
+	"strings"
+	"testing"
+)
+
+const websiteID = "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+
+var visitor = tracking.Visitor{UserAgent: "Mozilla/5.0", IP: "203.0.113.7", Language: "en-US"}
+
+func TestClient_TrackPageview(t *testing.T) {
+	public := publictest.New()
+	c := tracking.NewClient(public, websiteID, tracking.WithHostname("example.com"))
+
+	_, err := c.TrackPageview(context.Background(), visitor, tracking.Page{URL: "/pricing", Title: "Pricing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := public.Next(t)
+	p := s.Event.Payload
+	if s.Event.Type != tracking.TypeEvent || p.Name != "" || p.URL != "/pricing" || p.Hostname != "example.com" || p.Website != websiteID {
+		t.Errorf("unexpected pageview: %+v", s.Event)
+	}
+	if s.UserAgent != "Mozilla/5.0" || s.Options.ClientIP != "203.0.113.7" || p.Language != "en-US" {
+		t.Errorf("visitor not forwarded: %+v", s)
+	}
+}
+
+func TestClient_TrackEventAndIdentify(t *testing.T) {
+	public := publictest.New()
+	c := tracking.NewClient(public, websiteID)
+
+	_, err := c.TrackEvent(context.Background(), visitor, tracking.Page{URL: "/"}, "signup", map[string]any{"plan": "pro"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := public.Next(t).Event; e.Payload.Name != "signup" || e.Payload.Data["plan"] != "pro" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+
+	_, err = c.Identify(context.Background(), visitor, "user-42", map[string]any{"company": "acme"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := public.Next(t).Event; e.Type != tracking.TypeIdentify || e.Payload.ID != "user-42" || e.Payload.Data["company"] != "acme" {
+		t.Errorf("unexpected identify: %+v", e)
+	}
+}
+
+func TestClient_TrackRevenue(t *testing.T) {
+	public := publictest.New()
+	c := tracking.NewClient(public, websiteID)
+
+	_, err := c.TrackRevenue(context.Background(), visitor, tracking.Page{URL: "/checkout"}, "purchase", 19.99, "USD", map[string]any{"sku": "A1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := public.Next(t).Event.Payload.Data
+	if data[tracking.RevenueKey] != 19.99 || data[tracking.CurrencyKey] != "USD" || data["sku"] != "A1" {
+		t.Errorf("unexpected revenue data: %+v", data)
+	}
+}
+
+func TestClient_Validation(t *testing.T) {
+	c := tracking.NewClient(publictest.New(), websiteID)
+	ctx := context.Background()
+	page := tracking.Page{URL: "/"}
+
+	tests := map[string]func() error{
+		"missing url": func() error {
+			_, err := c.TrackPageview(ctx, visitor, tracking.Page{})
+			return err
+		},
+		"missing user agent": func() error {
+			_, err := c.TrackPageview(ctx, tracking.Visitor{}, page)
+			return err
+		},
+		"missing website": func() error {
+			_, err := tracking.NewClient(publictest.New(), "").TrackPageview(ctx, visitor, page)
+			return err
+		},
+		"empty event name": func() error {
+			_, err := c.TrackEvent(ctx, visitor, page, " ", nil)
+			return err
+		},
+		"malformed website": func() error {
+			_, err := tracking.NewClient(publictest.New(), "w1").TrackPageview(ctx, visitor, page)
+			return err
+		},
+		"unsupported data": func() error {
+			_, err := c.TrackEvent(ctx, visitor, page, "click", map[string]any{"fn": func() {}})
+			return err
+		},
+		"unsupported data in array": func() error {
+			_, err := c.Identify(ctx, visitor, "user-42", map[string]any{"tags": []any{"a", func() {}}})
+			return err
+		},
+		"empty identify": func() error {
+			_, err := c.Identify(ctx, visitor, "", nil)
+			return err
+		},
+		"bad currency": func() error {
+			_, err := c.TrackRevenue(ctx, visitor, page, "purchase", 10, "usd", nil)
+			return err
+		},
+		"infinite amount": func() error {
+			_, err := c.TrackRevenue(ctx, visitor, page, "purchase", math.Inf(1), "USD", nil)
+			return err
+		},
+	}
+
+	for name, call := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := call(); !errors.Is(err, validate.ErrInvalid) {
+				t.Errorf("expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_TruncatesLikeSend(t *testing.T) {
+	public := publictest.New()
+	c := tracking.NewClient(public, websiteID)
+
+	_, err := c.TrackEvent(context.Background(), visitor, tracking.Page{URL: "/"}, strings.Repeat("n", 51), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := public.Next(t).Event.Payload.Name; len(got) != 50 {
+		t.Errorf("expected the event name truncated to 50 characters, got %d", len(got))
+	}
+
+	strict := validate.Default()
+	strict.Mode = validate.Reject
+	_, err = tracking.NewClient(public, websiteID, tracking.WithValidator(strict)).
+		TrackEvent(context.Background(), visitor, tracking.Page{URL: "/"}, strings.Repeat("n", 51), nil)
+	if !errors.Is(err, validate.ErrInvalid) {
+		t.Errorf("expected ErrInvalid in Reject mode, got %v", err)
+	}
+}
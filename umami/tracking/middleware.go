@@ -0,0 +1,317 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"github.com/AdamShannag/umami-client/umami/api"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"net"
+	"net/http"
+	"net/netip"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrBusy is reported to the error handler for the pageviews dropped because WithMaxPending is reached.
+var ErrBusy = errors.New("tracking: too many pending pageviews")
+
+// Hook customizes the payload built for a request, e.g. to set a title or attach event data.
+type Hook func(r *http.Request, payload *types.SendEventPayload)
+
+// Option configures the Middleware.
+type Option func(*middleware)
+
+// WithClientIPHeaders sets the headers the client IP is read from, in order, such as
+// "CF-Connecting-IP" or "X-Forwarded-For". Lists are read from the right, skipping the addresses
+// of trusted proxies, since the left entries are set by the client. The headers are only read
+// for requests from a trusted proxy; the connection's remote address is used otherwise, and
+// when no header is set, which is the default.
+func WithClientIPHeaders(headers ...string) Option {
+	return func(m *middleware) {
+		m.ipHeaders = headers
+	}
+}
+
+// WithTrustedProxies sets the networks of the proxies allowed to set the client IP headers.
+// Without it, loopback and private addresses are trusted.
+func WithTrustedProxies(prefixes ...netip.Prefix) Option {
+	return func(m *middleware) {
+		m.trustedProxies = prefixes
+	}
+}
+
+// WithInclude only tracks paths matching one of the patterns. Patterns use path.Match syntax;
+// a pattern ending in "/**" matches every path under it.
+func WithInclude(patterns ...string) Option {
+	return func(m *middleware) {
+		m.include = append(m.include, patterns...)
+	}
+}
+
+// WithExclude never tracks paths matching one of the patterns, see WithInclude.
+func WithExclude(patterns ...string) Option {
+	return func(m *middleware) {
+		m.exclude = append(m.exclude, patterns...)
+	}
+}
+
+// WithHook registers hooks run on every payload before it is sent.
+func WithHook(hooks ...Hook) Option {
+	return func(m *middleware) {
+		m.hooks = append(m.hooks, hooks...)
+	}
+}
+
+// WithTimeout bounds each send (default 5 seconds).
+func WithTimeout(d time.Duration) Option {
+	return func(m *middleware) {
+		if d > 0 {
+			m.timeout = d
+		}
+	}
+}
+
+// WithMaxPending bounds the number of pageviews being sent at once (default 100). Pageviews
+// tracked while the limit is reached are dropped and reported to the error handler with ErrBusy.
+func WithMaxPending(n int) Option {
+	return func(m *middleware) {
+		if n > 0 {
+			m.maxPending = n
+		}
+	}
+}
+
+// WithErrorHandler sets a function called with the payload of a pageview that could not be sent.
+// It runs in the background, after the response was written.
+func WithErrorHandler(fn func(payload types.SendEventPayload, err error)) Option {
+	return func(m *middleware) {
+		m.onError = fn
+	}
+}
+
+type middleware struct {
+	public    api.Public
+	websiteID string
+
+	ipHeaders      []string
+	trustedProxies []netip.Prefix
+	include        []string
+	exclude        []string
+	hooks          []Hook
+	timeout        time.Duration
+	maxPending     int
+	onError        func(payload types.SendEventPayload, err error)
+
+	// pending holds a slot per pageview being sent.
+	pending chan struct{}
+}
+
+// Middleware returns net/http middleware tracking a pageview for every GET request answered with
+// a non-error status. Pageviews are sent through public in the background, so Umami latency never
+// delays the response.
+func Middleware(public api.Public, websiteID string, opts ...Option) func(http.Handler) http.Handler {
+	m := &middleware{
+		public:     public,
+		websiteID:  websiteID,
+		timeout:    5 * time.Second,
+		maxPending: 100,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.pending = make(chan struct{}, m.maxPending)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || !m.tracks(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+
+			if sw.status < http.StatusBadRequest {
+				m.track(r)
+			}
+		})
+	}
+}
+
+func (m *middleware) tracks(p string) bool {
+	for _, pattern := range m.exclude {
+		if matchPath(pattern, p) {
+			return false
+		}
+	}
+	if len(m.include) == 0 {
+		return true
+	}
+	for _, pattern := range m.include {
+		if matchPath(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *middleware) track(r *http.Request) {
+	payload := m.payload(r)
+	for _, hook := range m.hooks {
+		hook(r, &payload)
+	}
+
+	userAgent := r.UserAgent()
+	opts := []api.SendOption{
+		api.WithClientIP(payload.IP),
+		api.WithAcceptLanguage(r.Header.Get("Accept-Language")),
+	}
+	select {
+	case m.pending <- struct{}{}:
+	default:
+		m.report(payload, ErrBusy)
+		return
+	}
+
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		defer func() { <-m.pending }()
+		ctx, cancel := context.WithTimeout(ctx, m.timeout)
+		defer cancel()
+
+		_, err := m.public.Send(ctx, userAgent, types.SendEventRequest{Type: "event", Payload: payload}, opts...)
+		m.report(payload, err)
+	}()
+}
+
+func (m *middleware) report(payload types.SendEventPayload, err error) {
+	if err != nil && m.onError != nil {
+		m.onError(payload, err)
+	}
+}
+
+func (m *middleware) payload(r *http.Request) types.SendEventPayload {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return types.SendEventPayload{
+		Website:  m.websiteID,
+		Hostname: host,
+		Language: language(r.Header.Get("Accept-Language")),
+		Referrer: r.Referer(),
+		URL:      r.URL.RequestURI(),
+		IP:       m.clientIP(r),
+	}
+}
+
+// clientIP returns the client IP read from the first configured header holding a valid one when
+// the peer is a trusted proxy, and the peer address otherwise.
+func (m *middleware) clientIP(r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+
+	peerIP, err := netip.ParseAddr(peer)
+	if len(m.ipHeaders) == 0 || err != nil || !m.trusted(peerIP) {
+		return peer
+	}
+
+	for _, h := range m.ipHeaders {
+		if ip, ok := m.forwardedIP(r.Header.Values(h)); ok {
+			return ip.String()
+		}
+	}
+	return peer
+}
+
+// forwardedIP walks the addresses of a header from the right, as each proxy appends the address
+// it received the request from, and returns the first one that is not a trusted proxy. When every
+// address is trusted, the leftmost one is returned.
+func (m *middleware) forwardedIP(values []string) (netip.Addr, bool) {
+	var entries []string
+	for _, v := range values {
+		entries = append(entries, strings.Split(v, ",")...)
+	}
+
+	var leftmost netip.Addr
+	for i := len(entries) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(strings.TrimSpace(entries[i]))
+		if err != nil {
+			// Addresses left of a malformed entry cannot be attributed to a trusted proxy.
+			break
+		}
+		ip = ip.Unmap()
+		if !m.trusted(ip) {
+			return ip, true
+		}
+		leftmost = ip
+	}
+	return leftmost, leftmost.IsValid()
+}
+
+func (m *middleware) trusted(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if len(m.trustedProxies) == 0 {
+		return ip.IsLoopback() || ip.IsPrivate()
+	}
+	for _, prefix := range m.trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// language returns the preferred language of an Accept-Language header, e.g. "en-US" for "en-US,en;q=0.9".
+func language(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	tag, _, _ := strings.Cut(first, ";")
+	tag = strings.TrimSpace(tag)
+	if tag == "*" {
+		return ""
+	}
+	return tag
+}
+
+func matchPath(pattern, p string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return p == prefix || strings.HasPrefix(p, prefix+"/")
+	}
+	ok, _ := path.Match(pattern, p)
+	return ok
+}
+
+// statusWriter records the response status; Unwrap keeps http.ResponseController working and
+// Flush keeps streaming handlers asserting http.Flusher working.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
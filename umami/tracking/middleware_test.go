@@ -0,0 +1,159 @@
+package tracking_test
+
+import (
+	"errors"
+	"github.com/AdamShannag/umami-client/umami/internal/publictest"
+	"github.com/AdamShannag/umami-client/umami/tracking"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func serve(handler http.Handler, r *http.Request) {
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+}
+
+func TestMiddleware_BuildsPayload(t *testing.T) {
+	public := publictest.New()
+	handler := tracking.Middleware(public, "w1",
+		tracking.WithHook(func(r *http.Request, p *types.SendEventPayload) {
+			p.Data = map[string]any{"plan": "pro"}
+		}),
+	)(okHandler())
+
+	r := httptest.NewRequest(http.MethodGet, "http://docs.example.com:8080/api/intro?v=2", nil)
+	r.RemoteAddr = "203.0.113.7:51234"
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+	r.Header.Set("Referer", "https://google.com/")
+	r.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+	serve(handler, r)
+
+	s := public.Next(t)
+	p := s.Event.Payload
+	if s.UserAgent != "Mozilla/5.0" || s.Event.Type != "event" {
+		t.Errorf("unexpected send: %+v", s)
+	}
+	if p.Website != "w1" || p.Hostname != "docs.example.com" || p.URL != "/api/intro?v=2" {
+		t.Errorf("unexpected payload: %+v", p)
+	}
+	if p.Referrer != "https://google.com/" || p.Language != "de-DE" || p.IP != "203.0.113.7" {
+		t.Errorf("unexpected payload: %+v", p)
+	}
+	if s.Options.ClientIP != "203.0.113.7" || s.Options.AcceptLanguage != "de-DE,de;q=0.9,en;q=0.8" {
+		t.Errorf("unexpected send options: %+v", s.Options)
+	}
+	if p.Data["plan"] != "pro" {
+		t.Errorf("hook not applied: %+v", p.Data)
+	}
+}
+
+func TestMiddleware_SkipsUntrackedRequests(t *testing.T) {
+	public := publictest.New()
+	handler := tracking.Middleware(public, "w1",
+		tracking.WithInclude("/docs/**", "/missing"),
+		tracking.WithExclude("/docs/internal/**"),
+	)(okHandler())
+
+	serve(handler, httptest.NewRequest(http.MethodPost, "/docs/a", nil))
+	serve(handler, httptest.NewRequest(http.MethodGet, "/pricing", nil))
+	serve(handler, httptest.NewRequest(http.MethodGet, "/docs/internal/x", nil))
+	serve(handler, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	public.None(t)
+
+	serve(handler, httptest.NewRequest(http.MethodGet, "/docs/guides/setup", nil))
+	if got := public.Next(t).Event.Payload.URL; got != "/docs/guides/setup" {
+		t.Errorf("unexpected url %q", got)
+	}
+}
+
+func TestMiddleware_ClientIP(t *testing.T) {
+	xff := tracking.WithClientIPHeaders("X-Forwarded-For")
+	proxies := tracking.WithTrustedProxies(netip.MustParsePrefix("10.0.0.0/8"))
+
+	tests := []struct {
+		name      string
+		opts      []tracking.Option
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{"remote addr by default", nil, "10.0.0.1:1", "198.51.100.4", "10.0.0.1"},
+		{"private peer trusted by default", []tracking.Option{xff}, "10.0.0.1:1", "198.51.100.4", "198.51.100.4"},
+		{"public peer untrusted by default", []tracking.Option{xff}, "192.0.2.9:1", "198.51.100.4", "192.0.2.9"},
+		{"trusted proxy", []tracking.Option{xff, proxies}, "10.0.0.1:1", "198.51.100.4, 10.0.0.2", "198.51.100.4"},
+		{"spoofed leftmost entry", []tracking.Option{xff, proxies}, "10.0.0.1:1", "203.0.113.66, 198.51.100.4", "198.51.100.4"},
+		{"only proxies", []tracking.Option{xff, proxies}, "10.0.0.1:1", "10.0.0.3, 10.0.0.2", "10.0.0.3"},
+		{"malformed entry", []tracking.Option{xff, proxies}, "10.0.0.1:1", "198.51.100.4, bogus", "10.0.0.1"},
+		{"untrusted peer", []tracking.Option{xff, proxies}, "192.0.2.9:1", "198.51.100.4", "192.0.2.9"},
+		{"private peer outside the trusted proxies", []tracking.Option{xff, proxies}, "192.168.1.1:1", "198.51.100.4", "192.168.1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			public := publictest.New()
+			handler := tracking.Middleware(public, "w1", tt.opts...)(okHandler())
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+			serve(handler, r)
+
+			if got := public.Next(t).Event.Payload.IP; got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMiddleware_ForwardsFlush(t *testing.T) {
+	handler := tracking.Middleware(publictest.New(), "w1")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("response writer does not implement http.Flusher")
+		}
+		_, _ = w.Write([]byte("data: 1\n\n"))
+		f.Flush()
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if !rec.Flushed {
+		t.Error("flush not forwarded")
+	}
+}
+
+func TestMiddleware_MaxPending(t *testing.T) {
+	public := publictest.New()
+	public.Gate = make(chan struct{})
+	failed := make(chan error, 1)
+	handler := tracking.Middleware(public, "w1",
+		tracking.WithMaxPending(1),
+		tracking.WithErrorHandler(func(p types.SendEventPayload, err error) {
+			failed <- err
+		}),
+	)(okHandler())
+
+	serve(handler, httptest.NewRequest(http.MethodGet, "/a", nil))
+	serve(handler, httptest.NewRequest(http.MethodGet, "/b", nil))
+
+	if err := <-failed; !errors.Is(err, tracking.ErrBusy) {
+		t.Errorf("expected ErrBusy, got %v", err)
+	}
+	close(public.Gate)
+	if got := public.Next(t).Event.Payload.URL; got != "/a" {
+		t.Errorf("expected /a to be sent, got %s", got)
+	}
+	public.None(t)
+}
@@ -21,6 +21,9 @@ func (p ListQueryParams) ToQueryMap() map[string]string {
 	if p.OrderBy != "" {
 		q["orderBy"] = p.OrderBy
 	}
+	if p.SortDescending {
+		q["sortDescending"] = "true"
+	}
 
 	return q
 }
@@ -239,3 +242,16 @@ func buildBaseQueryParams(
 	}
 	return q
 }
+
+func (p RealtimeQueryParams) ToQueryMap() map[string]string {
+	q := make(map[string]string)
+
+	if !p.StartAt.IsZero() {
+		q["startAt"] = strconv.FormatInt(p.StartAt.UnixMilli(), 10)
+	}
+	if p.Timezone != "" {
+		q["timezone"] = p.Timezone
+	}
+
+	return q
+}
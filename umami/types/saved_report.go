@@ -0,0 +1,81 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Report types of saved reports.
+const (
+	ReportTypeInsights    = "insights"
+	ReportTypeFunnel      = "funnel"
+	ReportTypeRetention   = "retention"
+	ReportTypeUTM         = "utm"
+	ReportTypeGoals       = "goals"
+	ReportTypeJourney     = "journey"
+	ReportTypeRevenue     = "revenue"
+	ReportTypeAttribution = "attribution"
+)
+
+// RawParams holds the parameters of a saved report. Umami returns them either as a JSON
+// object or as a string containing JSON; both are normalised to the object form.
+type RawParams json.RawMessage
+
+func (p *RawParams) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		b = []byte(s)
+	}
+	*p = append((*p)[:0], b...)
+	return nil
+}
+
+func (p RawParams) MarshalJSON() ([]byte, error) {
+	if len(p) == 0 {
+		return []byte("null"), nil
+	}
+	return p, nil
+}
+
+// Params decodes the report parameters into the request type matching its Type, e.g.
+// ReportFunnelRequest for a funnel report. The report's website is used when the
+// parameters do not name one.
+func (r SavedReport) Params() (any, error) {
+	switch r.Type {
+	case ReportTypeInsights:
+		return decodeParams(r, func(p *ReportInsightsRequest) *string { return &p.WebsiteID })
+	case ReportTypeFunnel:
+		return decodeParams(r, func(p *ReportFunnelRequest) *string { return &p.WebsiteID })
+	case ReportTypeRetention:
+		return decodeParams(r, func(p *ReportRetentionRequest) *string { return &p.WebsiteID })
+	case ReportTypeUTM:
+		return decodeParams(r, func(p *ReportUTMRequest) *string { return &p.WebsiteID })
+	case ReportTypeGoals:
+		return decodeParams(r, func(p *ReportGoalsRequest) *string { return &p.WebsiteID })
+	case ReportTypeJourney:
+		return decodeParams(r, func(p *ReportJourneyRequest) *string { return &p.WebsiteID })
+	case ReportTypeRevenue:
+		return decodeParams(r, func(p *ReportRevenueRequest) *string { return &p.WebsiteID })
+	case ReportTypeAttribution:
+		return decodeParams(r, func(p *ReportAttributionRequest) *string { return &p.WebsiteID })
+	default:
+		return nil, fmt.Errorf("unsupported report type %q", r.Type)
+	}
+}
+
+func decodeParams[T any](r SavedReport, websiteID func(*T) *string) (T, error) {
+	var params T
+	if len(r.Parameters) > 0 {
+		if err := json.Unmarshal(r.Parameters, &params); err != nil {
+			return params, fmt.Errorf("decode %s report parameters: %w", r.Type, err)
+		}
+	}
+	if id := websiteID(&params); *id == "" {
+		*id = r.WebsiteID
+	}
+	return params, nil
+}
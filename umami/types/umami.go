@@ -1,16 +1,20 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Auth struct {
 	Token string `json:"token"`
 }
 
 type ListQueryParams struct {
-	Query    string `json:"query,omitempty"`    // Optional search string
-	Page     string `json:"page,omitempty"`     // Optional page number (default: 1)
-	PageSize string `json:"pageSize,omitempty"` // Optional results per page
-	OrderBy  string `json:"orderBy,omitempty"`  // Optional order column (default: name)
+	Query          string `json:"query,omitempty"`          // Optional search string
+	Page           string `json:"page,omitempty"`           // Optional page number (default: 1)
+	PageSize       string `json:"pageSize,omitempty"`       // Optional results per page
+	OrderBy        string `json:"orderBy,omitempty"`        // Optional order column (default: name)
+	SortDescending bool   `json:"sortDescending,omitempty"` // Optional descending order
 }
 
 type User struct {
@@ -55,6 +59,26 @@ type UpdateUserRequest struct {
 	Role     string `json:"role,omitempty"`
 }
 
+type CurrentUser struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	IsAdmin   bool      `json:"isAdmin"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Me struct {
+	Token      string      `json:"token"`
+	AuthKey    string      `json:"authKey"`
+	ShareToken *string     `json:"shareToken"`
+	User       CurrentUser `json:"user"`
+}
+
+type UpdatePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
 type UserWebsite struct {
 	ID        string     `json:"id"`
 	Name      string     `json:"name"`
@@ -575,16 +599,43 @@ type SendEventRequest struct {
 	Type    string           `json:"type"`
 }
 type SendEventPayload struct {
-	Website  string         `json:"website"`
-	Session  string         `json:"session,omitempty"`
-	Hostname string         `json:"hostname,omitempty"`
-	Language string         `json:"language,omitempty"`
-	Referrer string         `json:"referrer,omitempty"`
-	Screen   string         `json:"screen,omitempty"`
-	Title    string         `json:"title,omitempty"`
-	URL      string         `json:"url,omitempty"`
-	Name     string         `json:"name,omitempty"`
-	Data     map[string]any `json:"data,omitempty"`
+	Website   string         `json:"website"`
+	Session   string         `json:"session,omitempty"`
+	Hostname  string         `json:"hostname,omitempty"`
+	Language  string         `json:"language,omitempty"`
+	Referrer  string         `json:"referrer,omitempty"`
+	Screen    string         `json:"screen,omitempty"`
+	Title     string         `json:"title,omitempty"`
+	URL       string         `json:"url,omitempty"`
+	Name      string         `json:"name,omitempty"`
+	Data      map[string]any `json:"data,omitempty"`
+	IP        string         `json:"ip,omitempty"`        // Visitor IP when sending server-side
+	ID        string         `json:"id,omitempty"`        // Distinct ID of the visitor, set by identify calls
+	Timestamp int64          `json:"timestamp,omitempty"` // Unix seconds to record the event at instead of now (recent Umami versions)
+}
+
+type SendBatchResponse struct {
+	Size      int               `json:"size"`      // Number of events received
+	Processed int               `json:"processed"` // Number of events recorded
+	Errors    int               `json:"errors"`    // Number of events rejected
+	Details   []SendBatchDetail `json:"details"`   // One entry per rejected event
+}
+
+type SendBatchDetail struct {
+	Index    int             `json:"index"`    // Position of the event in the batch
+	Response json.RawMessage `json:"response"` // Error returned for the event
+}
+
+type SendResponse struct {
+	Cache     string `json:"cache"`     // Session cache token, sent back through the x-umami-cache header
+	SessionID string `json:"sessionId"` // Session the event was recorded in
+	VisitID   string `json:"visitId"`   // Visit the event was recorded in
+}
+
+// BatchItemResult is the outcome of one event sent through SendBatch.
+type BatchItemResult struct {
+	Index int   // Position of the event in the events passed to SendBatch
+	Err   error // Nil when the event was recorded
 }
 
 type Field struct {
@@ -770,3 +821,79 @@ type ReportRevenue struct {
 	Total   RevenueTotal `json:"total"`
 	Table   []Table      `json:"table"`
 }
+
+type SavedReport struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"userId"`
+	WebsiteID   string    `json:"websiteId"`
+	Type        string    `json:"type"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Parameters  RawParams `json:"parameters"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type SavedReports struct {
+	Data     []SavedReport `json:"data"`
+	Count    int64         `json:"count"`
+	Page     int64         `json:"page"`
+	PageSize int64         `json:"pageSize"`
+	OrderBy  string        `json:"orderBy"`
+}
+
+type SaveReportRequest struct {
+	WebsiteID   string `json:"websiteId"`
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Parameters  any    `json:"parameters"` // One of the Report*Request types matching Type
+}
+
+type RealtimeQueryParams struct {
+	StartAt  time.Time // Optional; only activity after this instant (default: last 30 minutes)
+	Timezone string    // Optional; timezone of the series buckets
+}
+
+type Realtime struct {
+	Countries map[string]int64 `json:"countries"` // Visitors per country code
+	URLs      map[string]int64 `json:"urls"`      // Pageviews per URL path
+	Referrers map[string]int64 `json:"referrers"` // Visitors per referrer domain
+	Events    []RealtimeEvent  `json:"events"`    // Most recent activity, newest first
+	Series    RealtimeSeries   `json:"series"`
+	Totals    RealtimeTotals   `json:"totals"`
+	Timestamp int64            `json:"timestamp"` // Unix milliseconds the snapshot was taken at
+}
+
+// Realtime event types.
+const (
+	RealtimeTypePageview = "pageview"
+	RealtimeTypeEvent    = "event"
+	RealtimeTypeSession  = "session"
+)
+
+type RealtimeEvent struct {
+	Type           string    `json:"__type"` // RealtimeTypePageview, RealtimeTypeEvent or RealtimeTypeSession
+	ID             string    `json:"id"`
+	SessionID      string    `json:"sessionId"`
+	EventName      string    `json:"eventName"`
+	URLPath        string    `json:"urlPath"`
+	ReferrerDomain string    `json:"referrerDomain"`
+	Browser        string    `json:"browser"`
+	OS             string    `json:"os"`
+	Device         string    `json:"device"`
+	Country        string    `json:"country"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type RealtimeSeries struct {
+	Views    []TimeSeriesDataPoint `json:"views"`
+	Visitors []TimeSeriesDataPoint `json:"visitors"`
+}
+
+type RealtimeTotals struct {
+	Views     int64 `json:"views"`
+	Visitors  int64 `json:"visitors"`
+	Events    int64 `json:"events"`
+	Countries int64 `json:"countries"`
+}
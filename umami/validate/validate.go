@@ -0,0 +1,264 @@
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"math"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalid is wrapped by every FieldError.
+var ErrInvalid = errors.New("validate: invalid payload")
+
+// Mode decides what happens to payloads exceeding the limits.
+type Mode int
+
+const (
+	// Truncate shortens fields and drops the data properties beyond the limits, including those
+	// nested too deeply or with over-long keys, and rejects what cannot be fixed, such as a
+	// malformed website ID, an empty data key or unsupported data values.
+	Truncate Mode = iota
+	// Reject returns an error for any payload exceeding the limits.
+	Reject
+	// PassThrough sends payloads unchecked.
+	PassThrough
+)
+
+// Limits are the maximum sizes Umami stores; zero disables a limit. Lengths count characters.
+type Limits struct {
+	URL        int
+	Title      int
+	Referrer   int
+	Hostname   int
+	Language   int
+	Screen     int
+	EventName  int
+	DataDepth  int // Nesting levels of data objects, top-level properties being level 1
+	DataProps  int // Properties once nested objects are flattened
+	DataKey    int // Length of a flattened property key, e.g. "cart.items"
+	DataString int // Length of a string property value
+}
+
+// DefaultLimits returns the column sizes of Umami's database schema.
+func DefaultLimits() Limits {
+	return Limits{
+		URL:        500,
+		Title:      500,
+		Referrer:   500,
+		Hostname:   100,
+		Language:   35,
+		Screen:     11,
+		EventName:  50,
+		DataDepth:  5,
+		DataProps:  100,
+		DataKey:    500,
+		DataString: 500,
+	}
+}
+
+// FieldError describes a field of a payload exceeding the limits.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Reason)
+}
+
+func (e *FieldError) Unwrap() error {
+	return ErrInvalid
+}
+
+// Validator checks tracking payloads against Umami's limits.
+type Validator struct {
+	Mode   Mode
+	Limits Limits
+}
+
+// Default returns a validator truncating payloads to the default limits.
+func Default() Validator {
+	return Validator{Mode: Truncate, Limits: DefaultLimits()}
+}
+
+// Validate checks p, truncating it in place in Truncate mode; data is copied rather than modified.
+// The error joins a FieldError per invalid field.
+func (v Validator) Validate(p *types.SendEventPayload) error {
+	if v.Mode == PassThrough {
+		return nil
+	}
+
+	var errs []error
+	if !isUUID(p.Website) {
+		errs = append(errs, &FieldError{Field: "website", Reason: fmt.Sprintf("%q is not a UUID", p.Website)})
+	}
+
+	for _, f := range []struct {
+		name  string
+		value *string
+		max   int
+	}{
+		{"url", &p.URL, v.Limits.URL},
+		{"title", &p.Title, v.Limits.Title},
+		{"referrer", &p.Referrer, v.Limits.Referrer},
+		{"hostname", &p.Hostname, v.Limits.Hostname},
+		{"language", &p.Language, v.Limits.Language},
+		{"screen", &p.Screen, v.Limits.Screen},
+		{"name", &p.Name, v.Limits.EventName},
+	} {
+		if err := v.text(f.name, f.value, f.max); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if p.Data != nil {
+		d := &dataWalker{v: v}
+		p.Data = d.object("", p.Data, 1)
+		errs = append(errs, d.errs...)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (v Validator) text(field string, s *string, max int) error {
+	if max <= 0 || utf8.RuneCountInString(*s) <= max {
+		return nil
+	}
+	if v.Mode == Truncate {
+		*s = truncate(*s, max)
+		return nil
+	}
+	return &FieldError{Field: field, Reason: fmt.Sprintf("exceeds %d characters", max)}
+}
+
+// dataWalker copies event data while checking it.
+type dataWalker struct {
+	v     Validator
+	props int
+	errs  []error
+}
+
+func (d *dataWalker) object(prefix string, m map[string]any, depth int) map[string]any {
+	limits := d.v.Limits
+
+	// Sorted keys make truncation deterministic.
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	out := make(map[string]any, len(m))
+	for _, k := range keys {
+		// key is the flattened key with a leading dot, e.g. ".cart.items".
+		key := prefix + "." + k
+		if k == "" {
+			d.errs = append(d.errs, &FieldError{Field: "data" + key, Reason: "has an empty key"})
+			continue
+		}
+		if limits.DataKey > 0 && utf8.RuneCountInString(key)-1 > limits.DataKey {
+			d.exceed(&FieldError{Field: "data" + key, Reason: fmt.Sprintf("key exceeds %d characters", limits.DataKey)})
+			continue
+		}
+
+		if nested, ok := m[k].(map[string]any); ok {
+			if limits.DataDepth > 0 && depth+1 > limits.DataDepth {
+				d.exceed(&FieldError{Field: "data" + key, Reason: fmt.Sprintf("nests deeper than %d levels", limits.DataDepth)})
+				continue
+			}
+			out[k] = d.object(key, nested, depth+1)
+			continue
+		}
+
+		if limits.DataProps > 0 && d.props >= limits.DataProps {
+			if d.v.Mode == Truncate {
+				continue
+			}
+			d.errs = append(d.errs, &FieldError{Field: "data", Reason: fmt.Sprintf("has more than %d properties", limits.DataProps)})
+			return out
+		}
+		d.props++
+		out[k] = d.value(key, m[k])
+	}
+	return out
+}
+
+// exceed records err unless the property is dropped in Truncate mode.
+func (d *dataWalker) exceed(err error) {
+	if d.v.Mode != Truncate {
+		d.errs = append(d.errs, err)
+	}
+}
+
+func (d *dataWalker) value(key string, v any) any {
+	switch t := v.(type) {
+	case string:
+		if err := d.v.text("data"+key, &t, d.v.Limits.DataString); err != nil {
+			d.errs = append(d.errs, err)
+		}
+		return t
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			d.errs = append(d.errs, &FieldError{Field: "data" + key, Reason: "is not a finite number"})
+		}
+		return t
+	case float32:
+		if math.IsNaN(float64(t)) || math.IsInf(float64(t), 0) {
+			d.errs = append(d.errs, &FieldError{Field: "data" + key, Reason: "is not a finite number"})
+		}
+		return t
+	case []any:
+		// Umami stores arrays as a single property, so their elements do not count as properties.
+		out := make([]any, len(t))
+		for i, item := range t {
+			out[i] = d.value(fmt.Sprintf("%s[%d]", key, i), item)
+		}
+		return out
+	case map[string]any:
+		// Objects within arrays are stored as part of the array.
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			out[k] = d.value(key+"."+k, item)
+		}
+		return out
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, time.Time,
+		[]string, []int, []float64, []bool:
+		return t
+	default:
+		d.errs = append(d.errs, &FieldError{Field: "data" + key, Reason: fmt.Sprintf("has unsupported type %T", v)})
+		return t
+	}
+}
+
+func truncate(s string, max int) string {
+	n := 0
+	for i := range s {
+		if n == max {
+			return s[:i]
+		}
+		n++
+	}
+	return s
+}
+
+func isUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package validate_test
+
+import (
+	"errors"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"github.com/AdamShannag/umami-client/umami/validate"
+	"strings"
+	"testing"
+)
+
+const websiteID = "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+
+func fieldErrors(err error) map[string]bool {
+	fields := map[string]bool{}
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fe *validate.FieldError
+		if errors.As(e, &fe) {
+			fields[fe.Field] = true
+		}
+	}
+	return fields
+}
+
+func TestValidate_Valid(t *testing.T) {
+	p := types.SendEventPayload{
+		Website: websiteID,
+		URL:     "/pricing",
+		Name:    "signup",
+		Data:    map[string]any{"plan": "pro", "seats": 3, "cart": map[string]any{"total": 9.5}},
+	}
+	if err := validate.Default().Validate(&p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate_Truncate(t *testing.T) {
+	data := map[string]any{"note": strings.Repeat("é", 600)}
+	p := types.SendEventPayload{
+		Website: websiteID,
+		URL:     "/" + strings.Repeat("a", 600),
+		Name:    strings.Repeat("n", 60),
+		Data:    data,
+	}
+
+	if err := validate.Default().Validate(&p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.URL) != 500 || len(p.Name) != 50 {
+		t.Errorf("expected truncated fields, got url %d, name %d", len(p.URL), len(p.Name))
+	}
+	if got := []rune(p.Data["note"].(string)); len(got) != 500 {
+		t.Errorf("expected note truncated to 500 characters, got %d", len(got))
+	}
+	if len(data["note"].(string)) != 1200 {
+		t.Error("caller data was modified")
+	}
+}
+
+func TestValidate_TruncateDropsExtraProperties(t *testing.T) {
+	v := validate.Default()
+	v.Limits.DataProps = 2
+
+	p := types.SendEventPayload{Website: websiteID, Data: map[string]any{"c": 3, "a": 1, "b": 2}}
+	if err := v.Validate(&p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(p.Data) != 2 || p.Data["c"] != nil {
+		t.Errorf("expected the last keys to be dropped, got %v", p.Data)
+	}
+}
+
+func TestValidate_TruncateDropsDeepAndLongKeys(t *testing.T) {
+	v := validate.Default()
+	v.Limits.DataDepth = 2
+	v.Limits.DataKey = 5
+
+	p := types.SendEventPayload{Website: websiteID, Data: map[string]any{
+		"a":        map[string]any{"b": 1, "c": map[string]any{"d": 1}},
+		"long_key": 1,
+		"ok":       1,
+	}}
+	if err := v.Validate(&p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := p.Data["a"].(map[string]any)
+	if _, ok := a["c"]; ok || a["b"] != 1 || p.Data["long_key"] != nil || p.Data["ok"] != 1 {
+		t.Errorf("expected too deep and long keys dropped, got %v", p.Data)
+	}
+}
+
+func TestValidate_ChecksArrayElements(t *testing.T) {
+	p := types.SendEventPayload{Website: websiteID, Data: map[string]any{
+		"tags":  []any{"ok", strings.Repeat("x", 600)},
+		"items": []any{map[string]any{"fn": func() {}}},
+		"":      1,
+	}}
+	err := validate.Default().Validate(&p)
+
+	fields := fieldErrors(err)
+	if !fields["data.items[0].fn"] || !fields["data."] || len(fields) != 2 {
+		t.Errorf("expected errors for data.items[0].fn and the empty key, got %v", err)
+	}
+	if got := p.Data["tags"].([]any)[1].(string); len(got) != 500 {
+		t.Errorf("expected array string truncated to 500 characters, got %d", len(got))
+	}
+}
+
+func TestValidate_Reject(t *testing.T) {
+	v := validate.Default()
+	v.Mode = validate.Reject
+	v.Limits.DataDepth = 2
+	v.Limits.DataProps = 1
+
+	p := types.SendEventPayload{
+		Website:  "not-a-uuid",
+		Title:    strings.Repeat("t", 501),
+		Hostname: strings.Repeat("h", 101),
+		Data: map[string]any{
+			"a": map[string]any{"b": map[string]any{"c": 1}},
+			"f": func() {},
+			"g": 1,
+		},
+	}
+	err := v.Validate(&p)
+	if !errors.Is(err, validate.ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+
+	fields := fieldErrors(err)
+	for _, f := range []string{"website", "title", "hostname", "data.a.b", "data"} {
+		if !fields[f] {
+			t.Errorf("expected an error for %s, got %v", f, err)
+		}
+	}
+}
+
+func TestValidate_TruncateRejectsUnfixable(t *testing.T) {
+	p := types.SendEventPayload{Website: websiteID, Data: map[string]any{"ch": make(chan int)}}
+	err := validate.Default().Validate(&p)
+	if !fieldErrors(err)["data.ch"] {
+		t.Errorf("expected unsupported type error, got %v", err)
+	}
+}
+
+func TestValidate_PassThrough(t *testing.T) {
+	p := types.SendEventPayload{URL: strings.Repeat("a", 1000)}
+	if err := (validate.Validator{Mode: validate.PassThrough}).Validate(&p); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(p.URL) != 1000 {
+		t.Error("payload was modified")
+	}
+}
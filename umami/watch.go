@@ -0,0 +1,158 @@
+package umami
+
+import (
+	"context"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"time"
+)
+
+// maxWatchBackoff caps the delay between polls after consecutive errors.
+const maxWatchBackoff = 5 * time.Minute
+
+// WatchUpdate is emitted by Client.Watch after every poll.
+//
+// When the consumer falls behind, pending updates are coalesced: the latest snapshot is kept
+// and the NewSessions and NewEvents of the skipped updates are carried over, so no activity is lost.
+type WatchUpdate struct {
+	// Time is when the poll completed.
+	Time time.Time
+	// ActiveUsers is the number of visitors in the last 5 minutes.
+	ActiveUsers int64
+	// Snapshot is the realtime activity of the website.
+	Snapshot types.Realtime
+	// NewSessions holds the first activity of every session not seen on the previous poll.
+	NewSessions []types.RealtimeEvent
+	// NewEvents holds the pageviews and events not seen on the previous poll.
+	// Both are empty on the first update, whose Snapshot holds the activity so far.
+	NewEvents []types.RealtimeEvent
+	// Err is set when the poll failed; the other fields then keep the last successful values.
+	// Polling goes on with an exponential backoff until a poll succeeds.
+	Err error
+}
+
+func (c *client) Watch(ctx context.Context, websiteID string, interval time.Duration) <-chan WatchUpdate {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	updates := make(chan WatchUpdate, 1)
+
+	go func() {
+		defer close(updates)
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			select {
+			case <-c.closed:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		var (
+			last  WatchUpdate
+			seen  map[string]struct{}
+			delay = interval
+		)
+		for {
+			update, err := c.poll(ctx, websiteID)
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				last.Time, last.Err = time.Now(), err
+				last.NewSessions, last.NewEvents = nil, nil
+				publish(updates, last)
+				delay = min(delay*2, max(maxWatchBackoff, interval))
+			} else {
+				update.NewSessions, update.NewEvents = diffRealtime(seen, update.Snapshot.Events)
+				seen = realtimeKeys(update.Snapshot.Events)
+				last = update
+				publish(updates, update)
+				delay = interval
+			}
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+
+	return updates
+}
+
+func (c *client) poll(ctx context.Context, websiteID string) (WatchUpdate, error) {
+	active, err := c.GetWebsiteActiveUsers(ctx, websiteID)
+	if err != nil {
+		return WatchUpdate{}, err
+	}
+	snapshot, err := c.GetRealtime(ctx, websiteID, types.RealtimeQueryParams{})
+	if err != nil {
+		return WatchUpdate{}, err
+	}
+	return WatchUpdate{Time: time.Now(), ActiveUsers: active.Visitors, Snapshot: snapshot}, nil
+}
+
+// publish sends u without blocking. If the consumer has not taken the previous update yet,
+// it is replaced by u, carrying over its new sessions and events.
+func publish(updates chan WatchUpdate, u WatchUpdate) {
+	select {
+	case pending := <-updates:
+		u.NewSessions = append(pending.NewSessions, u.NewSessions...)
+		u.NewEvents = append(pending.NewEvents, u.NewEvents...)
+	default:
+	}
+	// Watch is the only sender, so the buffer has room now.
+	updates <- u
+}
+
+// diffRealtime returns the sessions and events of current absent from the previous poll.
+// seen is nil on the first poll, for which there is no diff.
+func diffRealtime(seen map[string]struct{}, current []types.RealtimeEvent) (sessions, events []types.RealtimeEvent) {
+	if seen == nil {
+		return nil, nil
+	}
+
+	// Realtime events are listed newest first; walk them oldest first so that the
+	// first activity of a session is the one reported.
+	for i := len(current) - 1; i >= 0; i-- {
+		e := current[i]
+		if _, ok := seen[sessionKey(e)]; !ok {
+			seen[sessionKey(e)] = struct{}{}
+			sessions = append(sessions, e)
+		}
+		if e.Type == types.RealtimeTypeSession {
+			continue
+		}
+		if _, ok := seen[eventKey(e)]; !ok {
+			events = append(events, e)
+		}
+	}
+	return sessions, events
+}
+
+func realtimeKeys(events []types.RealtimeEvent) map[string]struct{} {
+	keys := make(map[string]struct{}, len(events)*2)
+	for _, e := range events {
+		keys[sessionKey(e)] = struct{}{}
+		keys[eventKey(e)] = struct{}{}
+	}
+	return keys
+}
+
+func sessionKey(e types.RealtimeEvent) string {
+	return "s|" + e.SessionID
+}
+
+// eventKey identifies an event; realtime events carry no ID on every Umami version.
+func eventKey(e types.RealtimeEvent) string {
+	if e.ID != "" {
+		return "e|" + e.ID
+	}
+	return "e|" + e.Type + "|" + e.SessionID + "|" + e.CreatedAt.Format(time.RFC3339Nano) + "|" + e.URLPath + "|" + e.EventName
+}
@@ -0,0 +1,123 @@
+package umami
+
+import (
+	"context"
+	"fmt"
+	"github.com/AdamShannag/umami-client/umami/types"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func realtimeResp(events string) *http.Response {
+	return mockJSONResp([]byte(fmt.Sprintf(`{"events": [%s], "timestamp": 1}`, events)))
+}
+
+func nextUpdate(t *testing.T, updates <-chan WatchUpdate) WatchUpdate {
+	t.Helper()
+	select {
+	case u, ok := <-updates:
+		if !ok {
+			t.Fatal("updates closed")
+		}
+		return u
+	case <-time.After(2 * time.Second):
+		t.Fatal("no update")
+	}
+	return WatchUpdate{}
+}
+
+func TestWatch_Diff(t *testing.T) {
+	var polls atomic.Int32
+	c := newMockClient(func(req *http.Request) *http.Response {
+		if req.URL.Path == "/api/websites/w1/active" {
+			return mockJSONResp([]byte(`{"visitors": 2}`))
+		}
+		if polls.Add(1) == 1 {
+			return realtimeResp(`{"__type":"pageview","sessionId":"s1","urlPath":"/"}`)
+		}
+		return realtimeResp(`{"__type":"event","sessionId":"s2","eventName":"signup"},
+			{"__type":"pageview","sessionId":"s1","urlPath":"/"}`)
+	})
+	defer c.Close()
+
+	updates := c.Watch(context.Background(), "w1", 10*time.Millisecond)
+
+	first := nextUpdate(t, updates)
+	assertNil(t, first.Err)
+	assertEqual(t, first.ActiveUsers, int64(2))
+	assertEqual(t, len(first.Snapshot.Events), 1)
+	assertEqual(t, len(first.NewEvents), 0)
+
+	second := nextUpdate(t, updates)
+	assertEqual(t, len(second.NewSessions), 1)
+	assertEqual(t, second.NewSessions[0].SessionID, "s2")
+	assertEqual(t, len(second.NewEvents), 1)
+	assertEqual(t, second.NewEvents[0].EventName, "signup")
+}
+
+func TestWatch_ErrorThenRecover(t *testing.T) {
+	var polls atomic.Int32
+	c := newMockClient(func(req *http.Request) *http.Response {
+		if req.URL.Path == "/api/websites/w1/active" && polls.Add(1) == 1 {
+			return &http.Response{StatusCode: http.StatusBadRequest, Body: http.NoBody, Header: http.Header{}}
+		}
+		if req.URL.Path == "/api/websites/w1/active" {
+			return mockJSONResp([]byte(`{"visitors": 1}`))
+		}
+		return realtimeResp("")
+	})
+	defer c.Close()
+
+	updates := c.Watch(context.Background(), "w1", 5*time.Millisecond)
+
+	failed := nextUpdate(t, updates)
+	if failed.Err == nil {
+		t.Fatal("expected error update")
+	}
+	recovered := nextUpdate(t, updates)
+	assertNil(t, recovered.Err)
+	assertEqual(t, recovered.ActiveUsers, int64(1))
+}
+
+func TestWatch_StopsOnCancelAndClose(t *testing.T) {
+	c := newMockClient(func(req *http.Request) *http.Response {
+		if req.URL.Path == "/api/websites/w1/active" {
+			return mockJSONResp([]byte(`{"visitors": 0}`))
+		}
+		return realtimeResp("")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	byCtx := c.Watch(ctx, "w1", time.Hour)
+	byClose := c.Watch(context.Background(), "w1", time.Hour)
+	nextUpdate(t, byCtx)
+	nextUpdate(t, byClose)
+
+	cancel()
+	c.Close()
+	for _, updates := range []<-chan WatchUpdate{byCtx, byClose} {
+		select {
+		case _, ok := <-updates:
+			if ok {
+				t.Fatal("unexpected update")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("watch did not stop")
+		}
+	}
+}
+
+func TestPublish_CoalescesPendingUpdate(t *testing.T) {
+	updates := make(chan WatchUpdate, 1)
+
+	publish(updates, WatchUpdate{ActiveUsers: 1, NewEvents: []types.RealtimeEvent{{EventName: "a"}}})
+	publish(updates, WatchUpdate{ActiveUsers: 2, NewEvents: []types.RealtimeEvent{{EventName: "b"}}})
+
+	got := <-updates
+	assertEqual(t, got.ActiveUsers, int64(2))
+	assertEqual(t, len(got.NewEvents), 2)
+	assertEqual(t, got.NewEvents[0].EventName, "a")
+	assertEqual(t, len(updates), 0)
+}